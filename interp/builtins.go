@@ -3,6 +3,8 @@ package interp
 
 import (
 	"go/ast"
+	"go/token"
+	"strconv"
 	"strings"
 )
 
@@ -14,20 +16,88 @@ func typeString(e ast.Expr) string {
 	case *ast.StarExpr:
 		return "*" + typeString(t.X)
 	case *ast.ArrayType:
-		// We only support slices (Len == nil). Fixed arrays are not yet supported.
-		return "[]" + typeString(t.Elt)
+		if t.Len == nil { return "[]" + typeString(t.Elt) }
+		// [N]T; [...]T only appears in composite literals
+		switch l := t.Len.(type) {
+		case *ast.BasicLit:
+			n, _ := strconv.ParseInt(l.Value, 0, 64)
+			return "[" + strconv.FormatInt(n, 10) + "]" + typeString(t.Elt)
+		case *ast.Ellipsis: return "[...]" + typeString(t.Elt)
+		}
+		return "[" + typeString(t.Len) + "]" + typeString(t.Elt)
 	case *ast.MapType:
 		return "map[" + typeString(t.Key) + "]" + typeString(t.Value)
+	case *ast.StructType:
+		// anonymous struct: struct{X int; Y int}
+		var fields []string
+		for _, f := range t.Fields.List {
+			ft := typeString(f.Type)
+			if len(f.Names) == 0 { fields = append(fields, ft); continue }
+			for _, n := range f.Names { fields = append(fields, n.Name + " " + ft) }
+		}
+		return "struct{" + strings.Join(fields, "; ") + "}"
 	case *ast.ChanType:
 		// Direction is ignored for runtime dynamics.
 		return "chan " + typeString(t.Value)
 	case *ast.SelectorExpr:
 		// No full package typing; reduce to identifier (e.g., sync.WaitGroup -> WaitGroup)
 		return typeString(t.Sel)
+	case *ast.InterfaceType:
+		// interface{} and any share one spelling; other literals list methods as
+		// "Name(params) results" and embedded constraint elements as written.
+		if t.Methods == nil || len(t.Methods.List) == 0 { return "any" }
+		var elems []string
+		for _, m := range t.Methods.List {
+			if len(m.Names) == 0 { elems = append(elems, typeString(m.Type)); continue }
+			for _, n := range m.Names { elems = append(elems, n.Name + strings.TrimPrefix(typeString(m.Type), "func")) }
+		}
+		return "interface{" + strings.Join(elems, "; ") + "}"
+	case *ast.IndexExpr:
+		// generic instantiation: Stack[int]
+		return typeString(t.X) + "[" + typeString(t.Index) + "]"
+	case *ast.IndexListExpr:
+		return typeString(t.X) + "[" + strings.Join(exprTypes(t.Indices), ", ") + "]"
+	case *ast.BinaryExpr:
+		// constraint union: ~int | ~float64
+		if t.Op == token.OR { return typeString(t.X) + " | " + typeString(t.Y) }
+	case *ast.UnaryExpr:
+		if t.Op == token.TILDE { return "~" + typeString(t.X) }
+	case *ast.FuncType:
+		s := "func(" + strings.Join(fieldTypes(t.Params), ", ") + ")"
+		res := fieldTypes(t.Results)
+		switch len(res) {
+		case 0:
+		case 1: s += " " + res[0]
+		default: s += " (" + strings.Join(res, ", ") + ")"
+		}
+		return s
+	case *ast.Ellipsis:
+		return "..." + typeString(t.Elt)
+	case *ast.ParenExpr:
+		return typeString(t.X)
 	}
 	return ""
 }
 
+// exprTypes renders a list of type expressions.
+func exprTypes(es []ast.Expr) []string {
+	out := make([]string, len(es))
+	for i, e := range es { out[i] = typeString(e) }
+	return out
+}
+
+// fieldTypes lists one type string per declared name in a parameter or result list.
+func fieldTypes(fl *ast.FieldList) []string {
+	if fl == nil { return nil }
+	var out []string
+	for _, f := range fl.List {
+		ft := typeString(f.Type)
+		if len(f.Names) == 0 { out = append(out, ft); continue }
+		for range f.Names { out = append(out, ft) }
+	}
+	return out
+}
+
 // parseMapType splits "map[Key]Val" into key and value type strings.
 func parseMapType(s string) (key, val string) {
 	s = strings.TrimSpace(s)
@@ -50,16 +120,30 @@ func parseMapType(s string) (key, val string) {
 	return "", ""
 }
 
-func zeroValue(typ string) any {
+// arrayType splits "[N]T" into its length and element type. N may name a
+// package-level constant.
+func (vm *Interpreter) arrayType(typ string) (n int, elem string, ok bool) {
+	if !strings.HasPrefix(typ, "[") || strings.HasPrefix(typ, "[]") { return 0, "", false }
+	end := strings.IndexByte(typ, ']'); if end < 0 { return 0, "", false }
+	l := typ[1:end]
+	if c, found := vm.globals.Vars[l]; found { return ToInt(c), typ[end+1:], true }
+	n, err := strconv.Atoi(l); if err != nil { return 0, "", false }
+	return n, typ[end+1:], true
+}
+
+func (vm *Interpreter) zeroValue(typ string) any {
+	typ = vm.unalias(typ)
 	switch typ {
-	case "int", "byte": return 0
-	case "float64": return 0.0
 	case "bool": return false
 	case "string": return ""
-	case "struct{}", "nil": return nil
+	case "struct{}", "nil", "any", "error", "": return nil
 	default:
+		if isNumericType(typ) { return numericZero[canonicalType(typ)] }
+		if strings.HasPrefix(typ, "func(") {
+			return nil
+		}
 		if strings.HasPrefix(typ, "*") {
-			return (*StructVal)(nil)
+			return &PointerVal{ElementType: typ[1:]}
 		}
 		if strings.HasPrefix(typ, "[]") {
 			return &SliceVal{ElementType: typ[2:], Data: []any{}}
@@ -69,15 +153,29 @@ func zeroValue(typ string) any {
 			return &MapVal{KeyType: k, ElementType: v, Data: map[string]any{}, Keys: map[string]any{}}
 		}
 		if strings.HasPrefix(typ, "chan ") {
-			return &ChannelVal{ElementType: typ[5:], C: make(chan any), Closed: false}
+			// nil channel: blocks forever and is never ready in a select
+			return &ChannelVal{ElementType: typ[5:]}
 		}
-		return &StructVal{TypeName: typ, Fields: map[string]any{}}
+		if n, elem, ok := vm.arrayType(typ); ok {
+			a := &ArrayVal{ElementType: elem, Data: make([]any, n)}
+			for i := range a.Data { a.Data[i] = vm.zeroValue(elem) }
+			return a
+		}
+		td := vm.lookupType(typ)
+		if td != nil && td.Kind == "interface" { return nil }
+		if td != nil && td.Kind == "named" { return &NamedVal{TypeName: typ, Value: vm.zeroValue(vm.underlyingType(typ))} }
+		sv := &StructVal{TypeName: typ, Fields: map[string]any{}}
+		if td != nil {
+			targs := instanceBindings(td, typ)
+			for _, f := range td.Fields { sv.Fields[f.Name] = vm.zeroValue(substTypes(f.Type, targs)) }
+		}
+		return sv
 	}
 }
 
 // --------------- Builtins -----------------------
 
-func builtinMake(typ string, args []any) any {
+func (vm *Interpreter) builtinMake(typ string, args []any) any {
 	// Slices: make([]T, len[, cap])
 	if strings.HasPrefix(typ, "[]") {
 		elem := typ[2:]
@@ -86,7 +184,7 @@ func builtinMake(typ string, args []any) any {
 		if len(args) >= 2 { capacity = ToInt(args[1]) }
 		if capacity < length { capacity = length }
 		data := make([]any, length, capacity)
-		for i := 0; i < length; i++ { data[i] = zeroValue(elem) }
+		for i := 0; i < length; i++ { data[i] = vm.zeroValue(elem) }
 		return &SliceVal{ElementType: elem, Data: data}
 	}
 	// Maps: make(map[K]V)
@@ -109,31 +207,46 @@ func builtinMake(typ string, args []any) any {
 }
 
 func builtinLen(v any) int {
-	switch x := v.(type) {
+	switch x := underlying(v).(type) {
 	case string: return len(x)
 	case *SliceVal: return len(x.Data)
+	case *ArrayVal: return len(x.Data)
 	case *MapVal: return len(x.Data)
 	default: return 0
 	}
 }
 
 func builtinCap(v any) int {
-	switch x := v.(type) {
+	switch x := underlying(v).(type) {
 	case *SliceVal: return cap(x.Data)
+	case *ArrayVal: return len(x.Data)
 	default: return 0
 	}
 }
 
-func builtinAppend(slice any, elems ...any) any {
-	s, ok := slice.(*SliceVal); if !ok { return slice }
-	for _, e := range elems {
-		if s.ElementType == "byte" { s.Data = append(s.Data, ToInt(e)&0xFF) } else { s.Data = append(s.Data, e) }
+func (vm *Interpreter) builtinAppend(slice any, elems ...any) (any, error) {
+	if n, ok := slice.(*NamedVal); ok {
+		v, err := vm.builtinAppend(n.Value, elems...); if err != nil { return nil, err }
+		return &NamedVal{TypeName: n.TypeName, Value: v}, nil
+	}
+	s, ok := slice.(*SliceVal); if !ok { return slice, nil }
+	vals := make([]any, len(elems))
+	for i, e := range elems {
+		v, err := vm.convertTo(s.ElementType, e); if err != nil { return nil, err }
+		vals[i] = v
 	}
-	return s
+	s.Data = append(s.Data, vals...)
+	return s, nil
 }
 
 func builtinCopy(dst any, src any) int {
-	d, ok1 := dst.(*SliceVal); s, ok2 := src.(*SliceVal)
+	if str, ok := underlying(src).(string); ok {
+		// copy([]byte, string) copies the bytes of the string
+		s := &SliceVal{ElementType: "byte"}
+		for i := 0; i < len(str); i++ { s.Data = append(s.Data, str[i]) }
+		src = s
+	}
+	d, ok1 := underlying(dst).(*SliceVal); s, ok2 := underlying(src).(*SliceVal)
 	if !ok1 || !ok2 { return 0 }
 	n := len(s.Data); if len(d.Data) < n { n = len(d.Data) }
 	for i := 0; i < n; i++ { d.Data[i] = s.Data[i] }
@@ -146,22 +259,65 @@ func builtinClose(ch any) any {
 	return nil
 }
 
+// conversionFunc is the callable value of a type name used as T(x); T()
+// yields the zero value.
+func (vm *Interpreter) conversionFunc(typ string) *Function {
+	return &Function{Name: typ, Native: func(args []any) (any, error) {
+		if len(args) == 0 { return vm.zeroValue(typ), nil }
+		return vm.convertValue(typ, args[0]), nil
+	}}
+}
+
+// convertValue converts v to typ like T(v). Values convert between defined
+// types with the same underlying type, and to and from that type.
+func (vm *Interpreter) convertValue(typ string, v any) any {
+	x := builtinConvert(vm.underlyingType(typ), underlying(v))
+	if td := vm.lookupType(typ); td != nil {
+		switch td.Kind {
+		case "named": return &NamedVal{TypeName: typ, Value: x}
+		case "struct":
+			if sv, ok := x.(*StructVal); ok { c := copyValue(sv).(*StructVal); c.TypeName = typ; return c }
+		}
+	}
+	return x
+}
+
 // Simple type conversion calls: string([]byte), float64(int), etc.
 func builtinConvert(typ string, v any) any {
+	if isNumericType(typ) {
+		if !isNumber(v) { v = ToFloat(v) }
+		return convertNumber(typ, v)
+	}
 	switch typ {
-	case "int": return ToInt(v)
-	case "float64": return ToFloat(v)
 	case "bool": return ToBool(v)
-	case "string": return ToString(v)
-	case "byte": return ToInt(v) & 0xFF
-	default: return v
+	case "string":
+		// string(r) is the UTF-8 encoding of the rune r
+		if isIntegerType(numericType(v)) { return string(rune(ToInt(v))) }
+		if s, ok := v.(*SliceVal); ok {
+			switch canonicalType(s.ElementType) {
+			case "uint8":
+				b := make([]byte, len(s.Data))
+				for i, e := range s.Data { b[i] = byte(ToInt(e)) }
+				return string(b)
+			case "int32":
+				r := make([]rune, len(s.Data))
+				for i, e := range s.Data { r[i] = rune(ToInt(e)) }
+				return string(r)
+			}
+		}
+		return ToString(v)
 	}
-}
-
-func isBuiltinType(name string) bool {
-	switch name {
-	case "int", "float64", "bool", "string", "byte":
-		return true
-	default:	return false
+	// []byte(s) and []rune(s)
+	if s, ok := v.(string); ok && strings.HasPrefix(typ, "[]") {
+		out := &SliceVal{ElementType: typ[2:], Data: []any{}}
+		switch canonicalType(typ[2:]) {
+		case "uint8": for i := 0; i < len(s); i++ { out.Data = append(out.Data, s[i]) }
+		case "int32": for _, r := range s { out.Data = append(out.Data, r) }
+		default: return v
+		}
+		return out
 	}
+	return v
 }
+
+func isBuiltinType(name string) bool { return name == "bool" || name == "string" || isNumericType(name) }
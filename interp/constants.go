@@ -0,0 +1,348 @@
+// interp/constants.go
+package interp
+
+import (
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"strings"
+)
+
+// constVal is a constant known exactly at declaration time. typ is its
+// declared type, or "untyped int", "untyped float", ... for untyped ones.
+type constVal struct {
+	val constant.Value
+	typ string
+}
+
+func isUntyped(typ string) bool { return len(typ) > 8 && typ[:8] == "untyped " }
+
+// untypedKind is the untyped type of an exact value.
+func untypedKind(v constant.Value) string {
+	switch v.Kind() {
+	case constant.Bool: return "untyped bool"
+	case constant.String: return "untyped string"
+	case constant.Int: return "untyped int"
+	case constant.Float: return "untyped float"
+	case constant.Complex: return "untyped complex"
+	}
+	return "untyped nil"
+}
+
+// defaultType is the type an untyped constant gets when used as a value.
+func defaultType(typ string) string {
+	switch typ {
+	case "untyped bool": return "bool"
+	case "untyped string": return "string"
+	case "untyped int": return "int"
+	case "untyped rune": return "int32"
+	case "untyped float": return "float64"
+	case "untyped complex": return "complex128"
+	}
+	return typ
+}
+
+// untypedRank orders untyped numeric kinds: in a mixed expression the
+// result takes the later kind, as 1 + 2.0 is an untyped float.
+func untypedRank(typ string) int {
+	switch typ {
+	case "untyped int": return 1
+	case "untyped rune": return 2
+	case "untyped float": return 3
+	case "untyped complex": return 4
+	}
+	return 0
+}
+
+func isIntegerType(typ string) bool {
+	switch canonicalType(typ) {
+	case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64", "uintptr", "untyped int", "untyped rune":
+		return true
+	}
+	return false
+}
+
+func isUnsignedType(typ string) bool { t := canonicalType(typ); return len(t) > 3 && t[:4] == "uint" }
+
+// constToValue converts an exact constant to a runtime value of type typ,
+// failing when it does not fit like Go's constant overflow errors.
+func constToValue(c constant.Value, typ string) (any, error) {
+	typ = canonicalType(typ)
+	switch {
+	case typ == "bool":
+		if c.Kind() == constant.Bool { return constant.BoolVal(c), nil }
+	case typ == "string":
+		if c.Kind() == constant.String { return constant.StringVal(c), nil }
+	case isIntegerType(typ):
+		i := constant.ToInt(c)
+		if i.Kind() != constant.Int { return nil, NewRuntimeError(fmt.Sprintf("constant %s truncated to integer", c)) }
+		if isUnsignedType(typ) {
+			u, exact := constant.Uint64Val(i)
+			v := convertNumber(typ, u)
+			if b, _ := intBits(v); !exact || b != u { return nil, NewRuntimeError(fmt.Sprintf("constant %s overflows %s", c, typ)) }
+			return v, nil
+		}
+		n, exact := constant.Int64Val(i)
+		v := convertNumber(typ, n)
+		if b, _ := intBits(v); !exact || int64(b) != n { return nil, NewRuntimeError(fmt.Sprintf("constant %s overflows %s", c, typ)) }
+		return v, nil
+	case typ == "float32" || typ == "float64":
+		f, _ := constant.Float64Val(constant.ToFloat(c))
+		return convertNumber(typ, f), nil
+	case typ == "complex64" || typ == "complex128":
+		z := constant.ToComplex(c)
+		re, _ := constant.Float64Val(constant.Real(z)); im, _ := constant.Float64Val(constant.Imag(z))
+		return convertNumber(typ, complex(re, im)), nil
+	}
+	return nil, NewRuntimeError(fmt.Sprintf("cannot use constant %s as %s value", c, typ))
+}
+
+// constValue is the runtime value of a constant. Untyped constants get their
+// default type, which they must fit.
+func (vm *Interpreter) constValue(c constVal) (any, error) {
+	if !isUntyped(c.typ) && !isNumericType(c.typ) && c.typ != "bool" && c.typ != "string" {
+		// defined types: type Weekday int
+		if u := vm.underlyingType(c.typ); u != c.typ {
+			v, err := vm.constValue(constVal{c.val, u}); if err != nil { return nil, err }
+			return &NamedVal{TypeName: vm.unalias(c.typ), Value: v}, nil
+		}
+		return vm.constValue(constVal{c.val, untypedKind(c.val)})
+	}
+	return constToValue(c.val, defaultType(c.typ))
+}
+
+// evalAs evaluates e for a location of type typ. An untyped constant is
+// converted exactly, so one that does not fit typ is an overflow error
+// instead of a wrapped value.
+func (vm *Interpreter) evalAs(e ast.Expr, typ string, env *Env) (any, error) {
+	if u := canonicalType(vm.underlyingType(typ)); isNumericType(u) || u == "bool" || u == "string" {
+		c, ok, err := vm.constExpr(e, -1, env); if err != nil { return nil, err }
+		if ok && isUntyped(c.typ) {
+			c, err := vm.convertConst(c, typ); if err != nil { return nil, err }
+			return vm.constValue(c)
+		}
+	}
+	return vm.evalExpr(e, env)
+}
+
+// convertConst gives a constant the type typ, as in a typed constant
+// declaration or a conversion T(c). Defined types check the value against
+// their underlying type.
+func (vm *Interpreter) convertConst(c constVal, typ string) (constVal, error) {
+	if u := vm.underlyingType(typ); u != typ {
+		c, err := vm.convertConst(c, u); c.typ = typ
+		return c, err
+	}
+	t := canonicalType(typ)
+	switch {
+	case t == "string" && isIntegerType(c.typ):
+		n, _ := constant.Int64Val(c.val)
+		return constVal{constant.MakeString(string(rune(n))), typ}, nil
+	case isIntegerType(t):
+		c.val = constant.ToInt(c.val)
+	case t == "float32" || t == "float64":
+		c.val = constant.ToFloat(c.val)
+	case t == "complex64" || t == "complex128":
+		c.val = constant.ToComplex(c.val)
+	}
+	if isNumericType(t) || t == "bool" || t == "string" {
+		v, err := constToValue(c.val, t); if err != nil { return c, err }
+		// Typed float constants are rounded to their type.
+		switch x := v.(type) {
+		case float32: c.val = constant.MakeFloat64(float64(x))
+		case float64: c.val = constant.MakeFloat64(x)
+		}
+	}
+	return constVal{c.val, typ}, nil
+}
+
+// lookupConst finds the constant name denotes in env, if it is one.
+func lookupConst(name string, env *Env) (constVal, bool) {
+	for e := env; e != nil; e = e.Parent {
+		if c, ok := e.Consts[name]; ok { return c, true }
+		if _, ok := e.Vars[name]; ok { return constVal{}, false }
+	}
+	return constVal{}, false
+}
+
+// defineConst binds a constant in env. Its runtime value is what ordinary
+// expressions see; constants that fit no type are only usable in other
+// constant expressions.
+func (vm *Interpreter) defineConst(name string, c constVal, env *Env) {
+	if env.Consts == nil { env.Consts = map[string]constVal{} }
+	env.Consts[name] = c
+	if v, err := vm.constValue(c); err == nil { env.Vars[name] = v }
+}
+
+// declareConsts declares the specs of a const block. iota is the index of
+// the spec, and a spec without values repeats the type and expressions of
+// the previous one.
+func (vm *Interpreter) declareConsts(gd *ast.GenDecl, env *Env) error {
+	var last *ast.ValueSpec
+	for iota, sp := range gd.Specs {
+		vs := sp.(*ast.ValueSpec)
+		if len(vs.Values) == 0 && vs.Type == nil && last != nil { vs = &ast.ValueSpec{Names: vs.Names, Type: last.Type, Values: last.Values} } else { last = vs }
+		if len(vs.Values) < len(vs.Names) { return NewRuntimeError("missing init expr for const declaration") }
+		if len(vs.Values) > len(vs.Names) { return NewRuntimeError("extra init expr") }
+		typ := ""
+		if vs.Type != nil { typ = vm.resolveType(typeString(vs.Type), env) }
+		for i, name := range vs.Names {
+			c, ok, err := vm.constExpr(vs.Values[i], iota, env); if err != nil { return err }
+			if !ok {
+				// Not constant to this interpreter (e.g. a package variable): evaluate it once.
+				v, err := vm.evalExpr(vs.Values[i], env); if err != nil { return err }
+				if name.Name == "_" { continue }
+				if typ == "" { vm.declare(name.Name, v, env); continue }
+				if err := vm.declareTyped(name.Name, typ, v, env); err != nil { return err }
+				continue
+			}
+			if typ != "" { c, err = vm.convertConst(c, typ); if err != nil { return err } }
+			if name.Name != "_" { vm.defineConst(name.Name, c, env) }
+		}
+	}
+	return nil
+}
+
+// constExpr evaluates e exactly if it is a constant expression; ok is false
+// otherwise. iota is the index of the enclosing const spec.
+func (vm *Interpreter) constExpr(e ast.Expr, iota int, env *Env) (c constVal, ok bool, err error) {
+	switch ex := e.(type) {
+	case *ast.BasicLit:
+		v := constant.MakeFromLiteral(ex.Value, ex.Kind, 0)
+		if v.Kind() == constant.Unknown { return c, false, NewRuntimeError("invalid literal " + ex.Value) }
+		if ex.Kind == token.CHAR { return constVal{v, "untyped rune"}, true, nil }
+		return constVal{v, untypedKind(v)}, true, nil
+	case *ast.Ident:
+		if c, ok := lookupConst(ex.Name, env); ok { return c, true, nil }
+		if ex.Name == "iota" && iota >= 0 {
+			if _, declared := vm.get(ex.Name, env); !declared { return constVal{constant.MakeInt64(int64(iota)), "untyped int"}, true, nil }
+		}
+	case *ast.ParenExpr:
+		return vm.constExpr(ex.X, iota, env)
+	case *ast.SelectorExpr:
+		id, isIdent := ex.X.(*ast.Ident); if !isIdent { return c, false, nil }
+		if p, isPkg := vm.globals.Vars[id.Name].(*Package); isPkg {
+			if v, ok := p.Consts[ex.Sel.Name]; ok { return constVal{v, untypedKind(v)}, true, nil }
+		}
+	case *ast.UnaryExpr:
+		x, ok, err := vm.constExpr(ex.X, iota, env); if !ok || err != nil { return c, ok, err }
+		// ^ on an unsigned constant flips only the bits of its type
+		prec := uint(0)
+		if u := vm.underlyingType(x.typ); ex.Op == token.XOR && isUnsignedType(u) { prec = uint(8 * sizeOf(numericZero[canonicalType(u)])) }
+		return vm.typedConst(constant.UnaryOp(ex.Op, x.val, prec), x.typ)
+	case *ast.BinaryExpr:
+		x, ok, err := vm.constExpr(ex.X, iota, env); if !ok || err != nil { return c, ok, err }
+		y, ok, err := vm.constExpr(ex.Y, iota, env); if !ok || err != nil { return c, ok, err }
+		return vm.binaryConst(ex.Op, x, y)
+	case *ast.CallExpr:
+		if len(ex.Args) != 1 { return c, false, nil }
+		fn, isIdent := ex.Fun.(*ast.Ident); if !isIdent { return c, false, nil }
+		if _, shadowed := vm.get(fn.Name, env); shadowed { return c, false, nil }
+		x, ok, err := vm.constExpr(ex.Args[0], iota, env); if !ok || err != nil { return c, ok, err }
+		if fn.Name == "len" {
+			if x.val.Kind() != constant.String { return c, false, nil }
+			return constVal{constant.MakeInt64(int64(len(constant.StringVal(x.val)))), "int"}, true, nil
+		}
+		if isBuiltinType(fn.Name) {
+			c, err := vm.convertConst(x, fn.Name)
+			return c, err == nil, err
+		}
+	}
+	return c, false, nil
+}
+
+// binaryConst applies a binary operator to two constants. An untyped
+// operand takes the type of a typed one.
+func (vm *Interpreter) binaryConst(op token.Token, x, y constVal) (constVal, bool, error) {
+	if op == token.SHL || op == token.SHR {
+		s, ok := constant.Uint64Val(constant.ToInt(y.val))
+		if !ok { return constVal{}, false, NewRuntimeError(fmt.Sprintf("invalid shift count %s", y.val)) }
+		v := constant.ToInt(x.val)
+		if v.Kind() != constant.Int { return constVal{}, false, NewRuntimeError(fmt.Sprintf("invalid shift of %s", x.val)) }
+		typ := x.typ; if isUntyped(typ) { typ = "untyped int" }
+		return vm.typedConst(constant.Shift(v, op, uint(s)), typ)
+	}
+	typ := x.typ
+	switch {
+	case isUntyped(x.typ) && isUntyped(y.typ):
+		if untypedRank(y.typ) > untypedRank(x.typ) { typ = y.typ }
+	case isUntyped(x.typ):
+		typ = y.typ
+	case !isUntyped(y.typ) && x.typ != y.typ:
+		return constVal{}, false, NewRuntimeError(fmt.Sprintf("invalid operation: mismatched types %s and %s", x.typ, y.typ))
+	}
+	switch op {
+	case token.EQL, token.NEQ, token.LSS, token.LEQ, token.GTR, token.GEQ:
+		return constVal{constant.MakeBool(constant.Compare(x.val, op, y.val)), "untyped bool"}, true, nil
+	case token.QUO, token.REM:
+		if constant.Sign(y.val) == 0 { return constVal{}, false, NewRuntimeError("invalid operation: division by zero") }
+		// integer division truncates
+		if op == token.QUO && isIntegerType(vm.underlyingType(typ)) { op = token.QUO_ASSIGN }
+	}
+	return vm.typedConst(constant.BinaryOp(x.val, op, y.val), typ)
+}
+
+// typedConst checks that the result of a constant operation fits its type.
+func (vm *Interpreter) typedConst(v constant.Value, typ string) (constVal, bool, error) {
+	if v.Kind() == constant.Unknown { return constVal{}, false, NewRuntimeError("invalid constant operation") }
+	if isUntyped(typ) { return constVal{v, typ}, true, nil }
+	c, err := vm.convertConst(constVal{v, untypedKind(v)}, typ)
+	return c, err == nil, err
+}
+
+// sizeOf is the size in bytes of a fixed-size number.
+func sizeOf(v any) int {
+	switch v.(type) {
+	case int8, uint8: return 1
+	case int16, uint16: return 2
+	case int32, uint32, float32: return 4
+	}
+	return 8
+}
+
+// untypedOperands gives a constant operand of a binary expression the type
+// of the other operand, as s[i] == 'a' compares bytes. Operands that already
+// agree, and constants that do not fit, are left alone.
+// interfaceTyped reports whether the static type of e is an interface, as
+// far as it is known: variables declared with an interface type, calls
+// returning one and conversions such as any(x).
+func (vm *Interpreter) interfaceTyped(e ast.Expr, env *Env) bool {
+	typ := ""
+	switch x := ast.Unparen(e).(type) {
+	case *ast.Ident:
+		typ = declaredType(x.Name, env)
+	case *ast.CallExpr:
+		if id, ok := ast.Unparen(x.Fun).(*ast.Ident); ok && vm.funcs[id.Name] != nil {
+			if rt := vm.funcs[id.Name].ResultTypes; len(rt) == 1 { typ = rt[0] }
+		} else if t := typeString(x.Fun); len(x.Args) == 1 { typ = t }
+	}
+	if typ == "any" || typ == "error" || strings.HasPrefix(typ, "interface{") { return true }
+	td := vm.lookupType(typ); return td != nil && td.Kind == "interface"
+}
+
+func (vm *Interpreter) untypedOperands(ex *ast.BinaryExpr, l, r any, env *Env) (any, any) {
+	if ex.Op == token.SHL || ex.Op == token.SHR { return l, r }
+	// An untyped constant takes the defined type of the other operand: w == 1.
+	// Compared with an interface it keeps its default type instead.
+	named := func(e ast.Expr, typ string) (any, bool) {
+		c, ok, _ := vm.constExpr(e, -1, env); if !ok || !isUntyped(c.typ) { return nil, false }
+		c, err := vm.convertConst(c, typ); if err != nil { return nil, false }
+		v, err := vm.constValue(c); return v, err == nil
+	}
+	ln, lok := l.(*NamedVal); rn, rok := r.(*NamedVal)
+	lok = lok && !vm.interfaceTyped(ex.X, env); rok = rok && !vm.interfaceTyped(ex.Y, env)
+	if lok && !rok {
+		if v, ok := named(ex.Y, ln.TypeName); ok { return l, v }
+	} else if rok && !lok {
+		if v, ok := named(ex.X, rn.TypeName); ok { return v, r }
+	}
+	lt, rt := numericType(underlying(l)), numericType(underlying(r))
+	if lt == rt || lt == "" || rt == "" { return l, r }
+	if c, ok, _ := vm.constExpr(ex.Y, -1, env); ok && isUntyped(c.typ) {
+		if v, err := constToValue(c.val, lt); err == nil { return l, v }
+	} else if c, ok, _ := vm.constExpr(ex.X, -1, env); ok && isUntyped(c.typ) {
+		if v, err := constToValue(c.val, rt); err == nil { return v, r }
+	}
+	return l, r
+}
@@ -1,12 +1,18 @@
 // interp/environment.go
 package interp
 
-import "sync"
+import (
+	"go/constant"
+	"sync"
+)
 
 // Env is a lexical scope chaining to a parent environment.
 type Env struct {
 	Vars   map[string]any
+	Types  map[string]string // static types of names declared with one (var x T, params)
+	Consts map[string]constVal // exact values of the constants declared here
 	Parent *Env
+	frame  *callFrame // set on the outermost scope of a function call
 }
 
 func NewEnv(parent *Env) *Env { return &Env{Vars: map[string]any{}, Parent: parent} }
@@ -17,6 +23,7 @@ type Package struct {
 	Funcs map[string]*Function
 	Types map[string]*TypeDef
 	Vars  map[string]any
+	Consts map[string]constant.Value // untyped constants such as math.MaxInt64
 }
 
 // Interpreter holds global state: functions, types, packages, natives.
@@ -26,22 +33,26 @@ type Interpreter struct {
 	funcs    map[string]*Function
 	natives  map[string]func(args []any) (any, error)
 	packages map[string]*Package
-
-	// frames is a stack of call frames for defer/panic handling.
-	frames []*callFrame
+	aliases  map[string]string // type A = B
 
 	// For optional coarse locking if user runs many goroutines touching shared state.
 	mu sync.Mutex
 }
 
 func NewInterpreter() *Interpreter {
+	globals := NewEnv(nil)
+	// Predeclared identifiers; user code may still shadow them.
+	globals.Vars["true"] = true
+	globals.Vars["false"] = false
+	globals.Vars["nil"] = nil
+	globals.Consts = map[string]constVal{"true": {constant.MakeBool(true), "untyped bool"}, "false": {constant.MakeBool(false), "untyped bool"}}
 	return &Interpreter{
-		globals:  NewEnv(nil),
+		globals:  globals,
 		types:    map[string]*TypeDef{},
 		funcs:    map[string]*Function{},
 		natives:  map[string]func(args []any) (any, error){},
 		packages: map[string]*Package{},
-		frames:   []*callFrame{},
+		aliases:  map[string]string{},
 	}
 }
 
@@ -62,57 +73,123 @@ func (vm *Interpreter) get(name string, env *Env) (any, bool) {
 	return nil, false
 }
 
-func (vm *Interpreter) set(name string, val any, env *Env) {
+// declare binds name in env. Like every store, it copies struct values so
+// variables never share a struct.
+func (vm *Interpreter) declare(name string, val any, env *Env) { env.Vars[name] = copyValue(val) }
+
+// declareTyped declares name with a static type; later assignments are checked against it.
+func (vm *Interpreter) declareTyped(name, typ string, val any, env *Env) error {
+	v, err := vm.convertTo(typ, val); if err != nil { return err }
+	if env.Types == nil { env.Types = map[string]string{} }
+	env.Vars[name] = v; env.Types[name] = typ
+	return nil
+}
+
+// declaredType is the static type name was declared with, or "" if none.
+func declaredType(name string, env *Env) string {
+	for e := env; e != nil; e = e.Parent { if _, ok := e.Vars[name]; ok { return e.Types[name] } }
+	return ""
+}
+
+// assign stores val into an existing variable, honouring its declared
+// type. Assigning to _ discards the value.
+func (vm *Interpreter) assign(name string, val any, env *Env) error {
+	if name == "_" { return nil }
 	for e := env; e != nil; e = e.Parent {
-		if _, ok := e.Vars[name]; ok { e.Vars[name] = val; return }
+		if _, ok := e.Vars[name]; ok {
+			if typ, ok := e.Types[name]; ok {
+				v, err := vm.convertTo(typ, val); if err != nil { return err }
+				val = v
+			} else {
+				val = copyValue(val)
+			}
+			e.Vars[name] = val
+			return nil
+		}
 	}
-	// If not found, create in current scope.
-	env.Vars[name] = val
+	return NewRuntimeError("undefined: " + name)
 }
 
-func (vm *Interpreter) declare(name string, val any, env *Env) { env.Vars[name] = val }
-
 // --------------- Lvalue references for assignments ---------------
 
 type Ref interface { Get() any; Set(any) error }
 
 type varRef struct{ vm *Interpreter; env *Env; name string }
 func (r *varRef) Get() any { v,_ := r.vm.get(r.name, r.env); return v }
-func (r *varRef) Set(v any) error { r.vm.set(r.name, v, r.env); return nil }
+func (r *varRef) Set(v any) error { return r.vm.assign(r.name, v, r.env) }
 
-type sliceIndexRef struct{ s *SliceVal; i int }
+type sliceIndexRef struct{ vm *Interpreter; s *SliceVal; i int }
 func (r *sliceIndexRef) Get() any { return r.s.Data[r.i] }
-func (r *sliceIndexRef) Set(v any) error { r.s.Data[r.i] = v; return nil }
+func (r *sliceIndexRef) Set(v any) error {
+	v, err := r.vm.convertTo(r.s.ElementType, v); if err != nil { return err }
+	r.s.Data[r.i] = v; return nil
+}
 
-type mapIndexRef struct{ m *MapVal; k any }
+type arrayIndexRef struct{ vm *Interpreter; a *ArrayVal; i int }
+func (r *arrayIndexRef) Get() any { return r.a.Data[r.i] }
+func (r *arrayIndexRef) Set(v any) error {
+	v, err := r.vm.convertTo(r.a.ElementType, v); if err != nil { return err }
+	r.a.Data[r.i] = v; return nil
+}
+
+type mapIndexRef struct{ vm *Interpreter; m *MapVal; k any }
 func (r *mapIndexRef) Get() any { v,_ := r.m.getByKey(r.k); return v }
 func (r *mapIndexRef) Set(v any) error {
+	v, err := r.vm.convertTo(r.m.ElementType, v); if err != nil { return err }
 	if v == nil { r.m.deleteByKey(r.k) } else { r.m.setByKey(r.k, v) }
 	return nil
 }
 
-type fieldRef struct{ s *StructVal; name string }
+type fieldRef struct{ vm *Interpreter; s *StructVal; name string }
 func (r *fieldRef) Get() any { return r.s.Fields[r.name] }
-func (r *fieldRef) Set(v any) error { r.s.Fields[r.name] = v; return nil }
+func (r *fieldRef) Set(v any) error {
+	v, err := r.vm.convertTo(r.vm.fieldType(r.s.TypeName, r.name), v); if err != nil { return err }
+	r.s.Fields[r.name] = v; return nil
+}
+
+// cellRef is an anonymous variable, as allocated by new(T) or &T{...}.
+type cellRef struct{ v any }
+func (r *cellRef) Get() any { return r.v }
+func (r *cellRef) Set(v any) error { r.v = copyValue(v); return nil }
+
+// refKey identifies the location a Ref denotes; pointers are equal when
+// their keys are.
+type refKey struct{ p any; name string }
+
+func refAddr(r Ref) refKey {
+	switch x := r.(type) {
+	case *varRef:
+		for e := x.env; e != nil; e = e.Parent { if _, ok := e.Vars[x.name]; ok { return refKey{e, x.name} } }
+	case *sliceIndexRef:
+		return refKey{p: &x.s.Data[x.i]}
+	case *arrayIndexRef:
+		return refKey{p: &x.a.Data[x.i]}
+	case *fieldRef:
+		return refKey{x.s, x.name}
+	}
+	return refKey{p: r}
+}
 
 // ------------------- Call frames for defer/panic ------------------
 
+// callFrame holds the deferred calls of one function invocation and, while
+// they run, the panic being propagated.
 type callFrame struct {
-	defers []func()
+	defers     []func() error
+	panicking  *panicError
+	recovered  bool
+	deferredBy *callFrame // frame that deferred this call, if any
+	results    []string   // named result parameters, declared in the frame's scope
 }
 
-func (vm *Interpreter) pushFrame() *callFrame {
-	fr := &callFrame{defers: []func(){}}
-	vm.frames = append(vm.frames, fr)
-	return fr
-}
-func (vm *Interpreter) currentFrame() *callFrame {
-	if len(vm.frames) == 0 { return nil }
-	return vm.frames[len(vm.frames)-1]
+// frameOf returns the frame of the function whose scope env belongs to.
+func frameOf(env *Env) *callFrame {
+	if s := frameScope(env); s != nil { return s.frame }
+	return nil
 }
-func (vm *Interpreter) popFrame() *callFrame {
-	if len(vm.frames) == 0 { return nil }
-	fr := vm.frames[len(vm.frames)-1]
-	vm.frames = vm.frames[:len(vm.frames)-1]
-	return fr
+
+// frameScope returns the outermost scope of the function env belongs to.
+func frameScope(env *Env) *Env {
+	for e := env; e != nil; e = e.Parent { if e.frame != nil { return e } }
+	return nil
 }
@@ -6,6 +6,9 @@ import (
 	"go/ast"
 	"go/parser"
 	"go/token"
+	"maps"
+	"reflect"
+	"slices"
 	"strings"
 )
 
@@ -35,62 +38,45 @@ func (vm *Interpreter) Run(src string) error {
 		}
 	}
 
-	// Collect top-level declarations.
+	// Collect type declarations first; functions and variables may refer to
+	// types declared further down.
+	for _, decl := range file.Decls {
+		gd, ok := decl.(*ast.GenDecl); if !ok || gd.Tok != token.TYPE { continue }
+		for _, spec := range gd.Specs { vm.declareType(spec.(*ast.TypeSpec)) }
+	}
+	// Types defined over struct types declared later: type B A; type A struct{...}
+	for _, td := range vm.types { vm.completeType(td) }
+
+	// Collect constants and functions; package-level variables are
+	// initialised afterwards, in dependency order, then init functions run.
+	var vars []*ast.ValueSpec
+	var inits []*Function
 	for _, decl := range file.Decls {
 		switch d := decl.(type) {
 		case *ast.GenDecl:
 			switch d.Tok {
-			case token.TYPE:
-				for _, spec := range d.Specs {
-					ts := spec.(*ast.TypeSpec)
-					switch tt := ts.Type.(type) {
-					case *ast.StructType:
-						td := &TypeDef{Name: ts.Name.Name, Kind: "struct", Fields: []FieldDef{}, Methods: map[string]*Function{}}
-						for _, f := range tt.Fields.List {
-							ft := typeString(f.Type)
-							for _, n := range f.Names { td.Fields = append(td.Fields, FieldDef{Name: n.Name, Type: ft}) }
-						}
-						vm.types[td.Name] = td
-					default:
-						// other type decls are ignored in this subset
-					}
-				}
-			case token.CONST, token.VAR:
-				for _, spec := range d.Specs {
-					vs := spec.(*ast.ValueSpec)
-					for i, name := range vs.Names {
-						if name.Name == "_" { continue }
-						var val any
-						if i < len(vs.Values) {
-							v, err := vm.evalExpr(vs.Values[i], global); if err != nil { return err }
-							val = v
-						} else {
-							val = zeroValue(typeString(vs.Type))
-						}
-						vm.declare(name.Name, val, global)
-					}
-				}
+			case token.CONST:
+				if err := vm.declareConsts(d, global); err != nil { return err }
+			case token.VAR:
+				for _, spec := range d.Specs { vars = append(vars, spec.(*ast.ValueSpec)) }
 			}
 		case *ast.FuncDecl:
 			fn := &Function{Name: d.Name.Name, Body: d.Body, Env: global}
-			// Params
-			if d.Type.Params != nil {
-				for i, f := range d.Type.Params.List {
-					for _, n := range f.Names { fn.Params = append(fn.Params, n.Name) }
-					// variadic if last param is *ast.Ellipsis
-					if i == len(d.Type.Params.List)-1 {
-						if _, ok := f.Type.(*ast.Ellipsis); ok { fn.IsVariadic = true }
-					}
-				}
-			}
+			setSignature(fn, d.Type)
 			// Method receiver?
 			if d.Recv != nil && len(d.Recv.List) > 0 {
 				rcv := d.Recv.List[0]
-				fn.RecvName = rcv.Names[0].Name
-				fn.RecvType = strings.TrimPrefix(typeString(rcv.Type), "*")
+				if len(rcv.Names) > 0 { fn.RecvName = rcv.Names[0].Name }
+				_, fn.RecvPtr = rcv.Type.(*ast.StarExpr)
+				// Methods of generic types name the type parameters: func (s *Stack[T]) Push(v T)
+				var targs []string
+				fn.RecvType, targs = splitTypeArgs(strings.TrimPrefix(typeString(rcv.Type), "*"))
+				for _, p := range targs { fn.TypeParams = append(fn.TypeParams, p); fn.Constraints = append(fn.Constraints, "any") }
 				td := vm.types[fn.RecvType]
 				if td == nil { td = &TypeDef{Name: fn.RecvType, Kind: "struct", Methods: map[string]*Function{}}; vm.types[fn.RecvType] = td }
 				td.Methods[fn.Name] = fn
+			} else if fn.Name == "init" {
+				inits = append(inits, fn) // init cannot be referred to
 			} else {
 				vm.funcs[fn.Name] = fn
 				vm.globals.Vars[fn.Name] = fn
@@ -98,8 +84,11 @@ func (vm *Interpreter) Run(src string) error {
 		}
 	}
 
-	// Execute main()
 	mainFn, ok := vm.funcs["main"]; if !ok { return NewRuntimeError("no main() function found") }
+	if err := vm.initVars(vars, global); err != nil { return err }
+	for _, fn := range inits { if _, err := vm.callFunction(fn, global, nil, nil); err != nil { return err } }
+
+	// Execute main()
 	_, err = vm.callFunction(mainFn, global, nil, nil)
 	return err
 }
@@ -109,76 +98,82 @@ func (vm *Interpreter) Run(src string) error {
 func (vm *Interpreter) evalExpr(e ast.Expr, env *Env) (any, error) {
 	switch ex := e.(type) {
 	case *ast.BasicLit:
-		switch ex.Kind {
-		case token.INT:
-			n := 0
-			for i := 0; i < len(ex.Value); i++ { c := ex.Value[i]; if c < '0' || c > '9' { break }; n = n*10 + int(c-'0') }
-			return n, nil
-		case token.FLOAT:
-			s := ex.Value; dot := strings.IndexByte(s, '.')
-			if dot < 0 { return ToFloat(s), nil }
-			intp := ToInt(s[:dot]); frac := 0.0; base := 1.0
-			for i := dot+1; i < len(s); i++ { frac = frac*10 + float64(s[i]-'0'); base *= 10 }
-			return float64(intp) + frac/base, nil
-		case token.STRING:
-			s := ex.Value; if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' { return s[1:len(s)-1], nil }; return s, nil
-		case token.CHAR:
-			v := ex.Value
-			if len(v) >= 3 && v[0] == '\'' && v[len(v)-1] == '\'' {
-				if v[1] == '\\' && len(v) >= 4 { switch v[2] { case 'n': return int('\n'), nil; case 't': return int('\t'), nil; default: return int(v[2]), nil } }
-				return int(v[1]), nil
-			}
-			return 0, NewRuntimeError("invalid character literal")
-		default:
-			return nil, NewRuntimeError(fmt.Sprintf("unsupported basic literal kind: %v", ex.Kind))
-		}
+		// Literals are untyped constants: decoded exactly, then given their
+		// default type (rune literals are int32, imaginary ones complex128).
+		c, _, err := vm.constExpr(ex, -1, env); if err != nil { return nil, err }
+		return vm.constValue(c)
 	case *ast.Ident:
-		if isBuiltinType(ex.Name) {
-			return &Function{Name: ex.Name, Native: func(args []any) (any, error) {
-				if len(args) == 0 { return zeroValue(ex.Name), nil }
-				return builtinConvert(ex.Name, args[0]), nil
-			}}, nil
+		if isBuiltinType(ex.Name) { return vm.conversionFunc(ex.Name), nil }
+		if v, ok := vm.get(ex.Name, env); ok {
+			// A type parameter used as a conversion: T(x)
+			if tb, ok := v.(*typeBinding); ok { return vm.conversionFunc(tb.typ), nil }
+			return v, nil
 		}
-		if v, ok := vm.get(ex.Name, env); ok { return v, nil }
 		if f, ok := vm.funcs[ex.Name]; ok { return f, nil }
 		if n, ok := vm.natives[ex.Name]; ok { return &Function{Name: ex.Name, Native: n}, nil }
-		if _, ok := vm.types[ex.Name]; ok { return ex.Name, nil }
+		if a, ok := vm.aliases[ex.Name]; ok { return vm.conversionFunc(vm.resolveType(a, env)), nil }
+		if td, ok := vm.types[ex.Name]; ok {
+			if td.Kind == "named" || td.Kind == "struct" { return vm.conversionFunc(ex.Name), nil }
+			return ex.Name, nil
+		}
+		// any(x) converts to the empty interface, which keeps x as it is
+		if ex.Name == "any" { return &Function{Name: "any", Params: []string{"x"}, Native: func(args []any) (any, error) { return args[0], nil }}, nil }
+		// a constant too large for any type
+		if c, ok := lookupConst(ex.Name, env); ok { _, err := vm.constValue(c); return nil, err }
 		return nil, NewRuntimeError("undefined: " + ex.Name)
 
+	case *ast.ArrayType:
+		// A slice type used as a conversion: []byte(s)
+		return vm.conversionFunc(vm.resolveType(typeString(ex), env)), nil
+
 	case *ast.UnaryExpr:
+		if ex.Op == token.AND { return vm.addressOf(ex.X, env) }
 		if ex.Op == token.ARROW {
 			// Receive from channel: <-ch  (single value; two-value handled in assign)
 			v, err := vm.evalExpr(ex.X, env); if err != nil { return nil, err }
 			ch, ok := v.(*ChannelVal); if !ok || ch == nil { return nil, NewRuntimeError("receive on non-channel") }
 			val, ok2 := <- ch.C
-			if !ok2 { return zeroValue(ch.ElementType), nil }
+			if !ok2 { return vm.zeroValue(ch.ElementType), nil }
 			return val, nil
 		}
 		v, err := vm.evalExpr(ex.X, env); if err != nil { return nil, err }
-		switch ex.Op {
-		case token.NOT:		return !ToBool(v), nil
-		case token.SUB:		if _, ok := v.(float64); ok { return -ToFloat(v), nil }; return -ToInt(v), nil
-		case token.ADD:		if _, ok := v.(float64); ok { return +ToFloat(v), nil }; return +ToInt(v), nil
-		case token.XOR:		return ^ToInt(v), nil
-		case token.AND:		return v, nil // address-of ignored
-		default:			return nil, NewRuntimeError("unsupported unary op")
+		// -c keeps the defined type of c
+		if n, ok := v.(*NamedVal); ok {
+			r, err := applyUnaryOp(ex.Op, n.Value); if err != nil { return nil, err }
+			return &NamedVal{TypeName: n.TypeName, Value: r}, nil
 		}
+		return applyUnaryOp(ex.Op, v)
+
+	case *ast.StarExpr:
+		v, err := vm.evalExpr(ex.X, env); if err != nil { return nil, err }
+		return vm.deref(v)
 
 	case *ast.BinaryExpr:
+		// Constant expressions are exact, so 1 << 100 >> 98 is 4.
+		if c, ok, err := vm.constExpr(ex, -1, env); ok || err != nil {
+			if err != nil { return nil, err }
+			return vm.constValue(c)
+		}
 		l, err := vm.evalExpr(ex.X, env); if err != nil { return nil, err }
 		r, err := vm.evalExpr(ex.Y, env); if err != nil { return nil, err }
+		l, r = vm.untypedOperands(ex, l, r, env)
 		return vm.applyBinaryOp(ex.Op, l, r)
 
 	case *ast.CallExpr:
 		// Builtins: make, len, cap, append, copy, close, delete, panic
 		if id, ok := ex.Fun.(*ast.Ident); ok {
+			// Conversions of constants are exact: uint64(math.MaxUint64)
+			if isBuiltinType(id.Name) && len(ex.Args) == 1 {
+				c, isConst, err := vm.constExpr(ex, -1, env); if err != nil { return nil, err }
+				if isConst { return vm.constValue(c) }
+			}
 			switch id.Name {
 			case "make":
 				if len(ex.Args) == 0 { return nil, NewRuntimeError("make: missing type") }
-				tstr := typeString(ex.Args[0])
+				tstr := vm.resolveType(typeString(ex.Args[0]), env)
 				var args []any
 				for _, a := range ex.Args[1:] { v, err := vm.evalExpr(a, env); if err != nil { return nil, err }; args = append(args, v) }
-				return builtinMake(tstr, args), nil
+				return vm.builtinMake(tstr, args), nil
 			case "len":
 				if len(ex.Args) != 1 { return 0, nil }
 				v, err := vm.evalExpr(ex.Args[0], env); if err != nil { return nil, err }
@@ -195,13 +190,17 @@ func (vm *Interpreter) evalExpr(e ast.Expr, env *Env) (any, error) {
 					// Support f(slice...) expansion if CallExpr.Ellipsis is set on last arg.
 					if ex.Ellipsis != token.NoPos && i == len(ex.Args[1:])-1 {
 						v, err := vm.evalExpr(a, env); if err != nil { return nil, err }
-						if sv, ok := v.(*SliceVal); ok { els = append(els, sv.Data...) } else { els = append(els, v) }
+						switch sv := underlying(v).(type) {
+						case *SliceVal: els = append(els, sv.Data...)
+						case string: for i := 0; i < len(sv); i++ { els = append(els, sv[i]) } // append([]byte, s...)
+						default: els = append(els, v)
+						}
 					} else {
 						v, err := vm.evalExpr(a, env); if err != nil { return nil, err }
 						els = append(els, v)
 					}
 				}
-				return builtinAppend(s, els...), nil
+				return vm.builtinAppend(s, els...)
 			case "copy":
 				if len(ex.Args) != 2 { return 0, nil }
 				dst, err := vm.evalExpr(ex.Args[0], env); if err != nil { return nil, err }
@@ -210,13 +209,38 @@ func (vm *Interpreter) evalExpr(e ast.Expr, env *Env) (any, error) {
 			case "close":
 				if len(ex.Args) != 1 { return nil, NewRuntimeError("close: need channel") }
 				v, err := vm.evalExpr(ex.Args[0], env); if err != nil { return nil, err }
+				if ch, ok := v.(*ChannelVal); ok && ch.C == nil { return nil, &panicError{value: "close of nil channel"} }
 				return builtinClose(v), nil
 			case "delete":
 				if len(ex.Args) != 2 { return nil, nil }
 				m, err := vm.evalExpr(ex.Args[0], env); if err != nil { return nil, err }
 				k, err := vm.evalExpr(ex.Args[1], env); if err != nil { return nil, err }
-				if mm, ok := m.(*MapVal); ok { mm.deleteByKey(k) }
+				if mm, ok := underlying(m).(*MapVal); ok { mm.deleteByKey(vm.valueAs(mm.KeyType, k)) }
 				return nil, nil
+			case "new":
+				if len(ex.Args) != 1 { return nil, NewRuntimeError("new: need type") }
+				typ := vm.resolveType(typeString(ex.Args[0]), env)
+				return &PointerVal{ElementType: typ, Ref: &cellRef{v: vm.zeroValue(typ)}}, nil
+			case "recover":
+				// Only a function deferred by a panicking frame stops the panic.
+				fr := frameOf(env)
+				if fr == nil || fr.deferredBy == nil || fr.deferredBy.panicking == nil { return nil, nil }
+				p := fr.deferredBy.panicking
+				fr.deferredBy.panicking = nil; fr.deferredBy.recovered = true
+				return p.value, nil
+			case "complex":
+				if len(ex.Args) != 2 { return nil, NewRuntimeError("complex: need real and imaginary part") }
+				re, err := vm.evalExpr(ex.Args[0], env); if err != nil { return nil, err }
+				im, err := vm.evalExpr(ex.Args[1], env); if err != nil { return nil, err }
+				if _, ok := re.(float32); ok { return complex(re.(float32), convertNumber("float32", im).(float32)), nil }
+				return complex(ToFloat(re), ToFloat(im)), nil
+			case "real", "imag":
+				if len(ex.Args) != 1 { return nil, NewRuntimeError(id.Name + ": need one argument") }
+				v, err := vm.evalExpr(ex.Args[0], env); if err != nil { return nil, err }
+				if c, ok := v.(complex64); ok { if id.Name == "real" { return real(c), nil }; return imag(c), nil }
+				c := convertNumber("complex128", v).(complex128)
+				if id.Name == "real" { return real(c), nil }
+				return imag(c), nil
 			case "panic":
 				if len(ex.Args) == 0 { return nil, &panicError{value: "panic"} }
 				v, err := vm.evalExpr(ex.Args[0], env); if err != nil { return nil, err }
@@ -231,66 +255,24 @@ func (vm *Interpreter) evalExpr(e ast.Expr, env *Env) (any, error) {
 					member, ok2 := vm.resolvePackageSelector(p, sel.Sel.Name)
 					if !ok2 { return nil, NewRuntimeError("unknown package member: " + pid.Name + "." + sel.Sel.Name) }
 					fn, ok3 := member.(*Function); if !ok3 { return nil, NewRuntimeError("package member is not function") }
-					// Evaluate args (including ... expansion)
-					var args []any
-					if ex.Ellipsis != token.NoPos && len(ex.Args) > 0 {
-						for i, a := range ex.Args {
-							if i == len(ex.Args)-1 {
-								v, err := vm.evalExpr(a, env); if err != nil { return nil, err }
-								if sv, ok := v.(*SliceVal); ok { args = append(args, sv.Data...) } else { args = append(args, v) }
-							} else {
-								v, err := vm.evalExpr(a, env); if err != nil { return nil, err }
-								args = append(args, v)
-							}
-						}
-					} else {
-						for _, a := range ex.Args { v, err := vm.evalExpr(a, env); if err != nil { return nil, err }; args = append(args, v) }
-					}
+					args, err := vm.evalArgs(ex, env); if err != nil { return nil, err }
 					return vm.callFunction(fn, env, nil, args)
 				}
 			}
 		}
 
-		// Method call on struct: obj.M(...)
+		// Method call obj.M(...), or a call of a func-valued field
 		if sel, ok := ex.Fun.(*ast.SelectorExpr); ok {
-			recv, err := vm.evalExpr(sel.X, env); if err != nil { return nil, err }
-			recvType := typeOfValue(vm, recv)
-			td := vm.types[recvType]; if td == nil || td.Methods == nil { return nil, NewRuntimeError("unknown method on type " + recvType) }
-			fn := td.Methods[sel.Sel.Name]; if fn == nil { return nil, NewRuntimeError("method not found: " + recvType + "." + sel.Sel.Name) }
-			args := []any{recv}
-			// Evaluate args (support last ... expansion)
-			if ex.Ellipsis != token.NoPos && len(ex.Args) > 0 {
-				for i, a := range ex.Args {
-					if i == len(ex.Args)-1 {
-						v, err := vm.evalExpr(a, env); if err != nil { return nil, err }
-						if sv, ok := v.(*SliceVal); ok { args = append(args, sv.Data...) } else { args = append(args, v) }
-					} else {
-						v, err := vm.evalExpr(a, env); if err != nil { return nil, err }
-						args = append(args, v)
-					}
-				}
-			} else {
-				for _, a := range ex.Args { v, err := vm.evalExpr(a, env); if err != nil { return nil, err }; args = append(args, v) }
-			}
-			return vm.callFunction(fn, env, &recv, args[1:])
+			fn, recv, err := vm.selectCallee(sel, env); if err != nil { return nil, err }
+			args, err := vm.evalArgs(ex, env); if err != nil { return nil, err }
+			return vm.callFunction(fn, env, recv, args)
 		}
 
 		// Normal function call
 		callee, err := vm.evalExpr(ex.Fun, env); if err != nil { return nil, err }
-		switch fn := callee.(type) {
+		switch fn := underlying(callee).(type) {
 		case *Function:
-			var args []any
-			// Handle foo(slice...) expansion
-			if ex.Ellipsis != token.NoPos && len(ex.Args) > 0 {
-				for i, a := range ex.Args {
-					if i == len(ex.Args)-1 {
-						v, err := vm.evalExpr(a, env); if err != nil { return nil, err }
-						if sv, ok := v.(*SliceVal); ok { args = append(args, sv.Data...) } else { args = append(args, v) }
-					} else { v, err := vm.evalExpr(a, env); if err != nil { return nil, err }; args = append(args, v) }
-				}
-			} else {
-				for _, a := range ex.Args { v, err := vm.evalExpr(a, env); if err != nil { return nil, err }; args = append(args, v) }
-			}
+			args, err := vm.evalArgs(ex, env); if err != nil { return nil, err }
 			return vm.callFunction(fn, env, nil, args)
 		default:
 			return nil, NewRuntimeError("not a function")
@@ -298,100 +280,93 @@ func (vm *Interpreter) evalExpr(e ast.Expr, env *Env) (any, error) {
 
 	case *ast.IndexExpr:
 		v, err := vm.evalExpr(ex.X, env); if err != nil { return nil, err }
+		// Explicit instantiation of a generic function: Max[int]
+		if fn, ok := v.(*Function); ok { return vm.instantiate(fn, []string{vm.resolveType(typeString(ex.Index), env)}) }
 		i, err := vm.evalExpr(ex.Index, env); if err != nil { return nil, err }
-		switch t := v.(type) {
+		switch t := arrayOf(v).(type) {
 		case *SliceVal:
-			ii := ToInt(i); if ii < 0 || ii >= len(t.Data) { return nil, NewRuntimeError("index out of range") }
+			ii := ToInt(i); if ii < 0 || ii >= len(t.Data) { return nil, indexOutOfRange(ii, len(t.Data)) }
+			return t.Data[ii], nil
+		case *ArrayVal:
+			ii := ToInt(i); if ii < 0 || ii >= len(t.Data) { return nil, indexOutOfRange(ii, len(t.Data)) }
 			return t.Data[ii], nil
 		case *MapVal:
-			val, _ := t.getByKey(i); return val, nil
+			val, ok := t.getByKey(vm.valueAs(t.KeyType, i)); if !ok { return vm.zeroValue(t.ElementType), nil }
+			return val, nil
 		case string:
-			idx := ToInt(i); if idx < 0 || idx >= len(t) { return nil, NewRuntimeError("index out of range") }
-			return int(t[idx]), nil
+			idx := ToInt(i); if idx < 0 || idx >= len(t) { return nil, indexOutOfRange(idx, len(t)) }
+			return t[idx], nil
 		default:	return nil, NewRuntimeError("indexing unsupported")
 		}
 
+	case *ast.IndexListExpr:
+		v, err := vm.evalExpr(ex.X, env); if err != nil { return nil, err }
+		fn, ok := v.(*Function); if !ok { return nil, NewRuntimeError("instantiation of non-generic value") }
+		targs := exprTypes(ex.Indices)
+		for i := range targs { targs[i] = vm.resolveType(targs[i], env) }
+		return vm.instantiate(fn, targs)
+
 	case *ast.SliceExpr:
 		v, err := vm.evalExpr(ex.X, env); if err != nil { return nil, err }
 		lo := 0; hi := -1
 		if ex.Low != nil { lv, err := vm.evalExpr(ex.Low, env); if err != nil { return nil, err }; lo = ToInt(lv) }
 		if ex.High != nil { hv, err := vm.evalExpr(ex.High, env); if err != nil { return nil, err }; hi = ToInt(hv) }
-		switch s := v.(type) {
-		case *SliceVal:
-			if hi < 0 || hi > len(s.Data) { hi = len(s.Data) }
-			if lo < 0 || lo > hi { return nil, NewRuntimeError("invalid slice indices") }
-			return &SliceVal{ElementType: s.ElementType, Data: s.Data[lo:hi]}, nil
-		case string:
-			if hi < 0 || hi > len(s) { hi = len(s) }
-			if lo < 0 || lo > hi { return nil, NewRuntimeError("invalid slice indices") }
-			return s[lo:hi], nil
-		default:	return nil, NewRuntimeError("slice unsupported")
+		// Slicing a slice or string of a defined type keeps the type.
+		if n, ok := v.(*NamedVal); ok {
+			if _, isArray := n.Value.(*ArrayVal); !isArray {
+				r, err := sliceValue(n.Value, lo, hi); if err != nil { return nil, err }
+				return &NamedVal{TypeName: n.TypeName, Value: r}, nil
+			}
 		}
+		return sliceValue(arrayOf(v), lo, hi)
 
 	case *ast.SelectorExpr:
 		// Package selector (pkg.Member)
 		if id, ok := ex.X.(*ast.Ident); ok {
 			if p, ok := vm.globals.Vars[id.Name].(*Package); ok {
+				if c, ok := p.Consts[ex.Sel.Name]; ok { return vm.constValue(constVal{c, untypedKind(c)}) }
 				m, ok2 := vm.resolvePackageSelector(p, ex.Sel.Name); if !ok2 { return nil, NewRuntimeError("unknown package member: " + id.Name + "." + ex.Sel.Name) }
 				return m, nil
 			}
 		}
-		// Struct field access is handled when receiver is *StructVal during method calls or via fieldRef in assignments.
+		// Method expression: T.Method or (*T).Method
+		if fn, ok, err := vm.methodExpr(ex, env); ok || err != nil { return fn, err }
 		recv, err := vm.evalExpr(ex.X, env); if err != nil { return nil, err }
-		sv, ok := recv.(*StructVal); if !ok { return nil, NewRuntimeError("selector on non-struct") }
-		return sv.Fields[ex.Sel.Name], nil
+		// Fields are reached through pointers implicitly: p.X is (*p).X
+		if sv := structOf(recv); sv != nil {
+			if f, ok := vm.fieldHolder(sv, ex.Sel.Name).Fields[ex.Sel.Name]; ok { return f, nil }
+		}
+		// Method value: the method bound to its receiver, f := obj.Method
+		fn, r, err := vm.selectMethod(ex.X, recv, ex.Sel.Name, env)
+		if err != nil {
+			if isNil(recv) { return nil, &panicError{value: nilDeref} }
+			return nil, err
+		}
+		return vm.boundMethod(fn, r, env), nil
 
 	case *ast.CompositeLit:
 		// Struct, slice, map literals.
-		typ := typeString(ex.Type)
-		if strings.HasPrefix(typ, "[]") {
-			elem := typ[2:]
-			lit := &SliceVal{ElementType: elem, Data: []any{}}
-			for _, elt := range ex.Elts {
-				v, err := vm.evalExpr(elt, env); if err != nil { return nil, err }
-				lit.Data = append(lit.Data, v)
-			}
-			return lit, nil
-		}
-		if strings.HasPrefix(typ, "map[") {
-			k, v := parseMapType(typ)
-			lit := &MapVal{KeyType: k, ElementType: v, Data: map[string]any{}, Keys: map[string]any{}}
-			for _, elt := range ex.Elts {
-				kv, ok := elt.(*ast.KeyValueExpr); if !ok { continue }
-				key, err := vm.evalExpr(kv.Key, env); if err != nil { return nil, err }
-				val, err := vm.evalExpr(kv.Value, env); if err != nil { return nil, err }
-				lit.setByKey(key, val)
-			}
-			return lit, nil
-		}
-		// Struct literal with keyed fields (package prefix reduced by typeString)
-		typ = strings.TrimPrefix(typ, "*")
-		td := vm.types[typ]
-		if td == nil || td.Kind != "struct" {
-			return nil, NewRuntimeError("unknown struct type: " + typ)
-		}
-		obj := &StructVal{TypeName: typ, Fields: map[string]any{}}
-		for _, f := range td.Fields { obj.Fields[f.Name] = zeroValue(f.Type) }
-		for _, elt := range ex.Elts {
-			kv, ok := elt.(*ast.KeyValueExpr); if !ok { continue }
-			key := kv.Key.(*ast.Ident).Name
-			val, err := vm.evalExpr(kv.Value, env); if err != nil { return nil, err }
-			obj.Fields[key] = val
-		}
-		return obj, nil
+		return vm.evalComposite(ex, vm.resolveType(typeString(ex.Type), env), env)
 
 	case *ast.ParenExpr:
 		return vm.evalExpr(ex.X, env)
 
+	case *ast.TypeAssertExpr:
+		if ex.Type == nil { return nil, NewRuntimeError("use of .(type) outside type switch") }
+		v, err := vm.evalExpr(ex.X, env); if err != nil { return nil, err }
+		typ := vm.resolveType(typeString(ex.Type), env)
+		if !vm.typeMatches(v, typ) {
+			if v == nil { return nil, &panicError{value: "interface conversion: interface is nil, not " + typ} }
+			return nil, &panicError{value: "interface conversion: interface {} is " + typeOfValue(vm, v) + ", not " + typ}
+		}
+		return v, nil
+
 	case *ast.FuncLit:
 		fn := &Function{Name: "<anon>", Body: ex.Body, Env: env}
-		if ex.Type.Params != nil {
-			for _, f := range ex.Type.Params.List {
-				for _, n := range f.Names {
-					fn.Params = append(fn.Params, n.Name)
-				}
-			}
-		}
+		setSignature(fn, ex.Type)
+		// Closures inside generic code see the enclosing type arguments.
+		for i, pt := range fn.ParamTypes { fn.ParamTypes[i] = vm.resolveType(pt, env) }
+		for i, rt := range fn.ResultTypes { fn.ResultTypes[i] = vm.resolveType(rt, env) }
 		return fn, nil
 
 	default:
@@ -407,11 +382,40 @@ const (
 	controlReturn
 	controlBreak
 	controlContinue
+	controlGoto
+	controlFallthrough
 )
 
-type controlFlow struct { kind controlKind; val any }
+// controlFlow is the outcome of a statement; label names the target of a
+// labeled break, continue or goto.
+type controlFlow struct { kind controlKind; val any; label string }
+
+// loopControl decides what a loop labeled label does with the outcome of one
+// iteration: stop reports whether the loop ends, handing out to its parent.
+func loopControl(c controlFlow, label string) (stop bool, out controlFlow) {
+	switch c.kind {
+	case controlBreak:
+		if c.label == "" || c.label == label { return true, controlFlow{} }
+		return true, c
+	case controlContinue:
+		if c.label == "" || c.label == label { return false, controlFlow{} }
+		return true, c
+	case controlReturn, controlGoto:
+		return true, c
+	}
+	return false, controlFlow{}
+}
+
+// breakOut consumes a break aimed at a switch or select labeled label.
+func breakOut(c controlFlow, label string) controlFlow {
+	if c.kind == controlBreak && (c.label == "" || c.label == label) { return controlFlow{} }
+	return c
+}
+
+func (vm *Interpreter) evalStmt(s ast.Stmt, env *Env) (controlFlow, error) { return vm.execStmt(s, "", env) }
 
-func (vm *Interpreter) evalStmt(s ast.Stmt, env *Env) (controlFlow, error) {
+// execStmt runs s, which carries label if it is the body of a LabeledStmt.
+func (vm *Interpreter) execStmt(s ast.Stmt, label string, env *Env) (controlFlow, error) {
 	switch st := s.(type) {
 	case *ast.ExprStmt:
 		_, err := vm.evalExpr(st.X, env)
@@ -421,42 +425,36 @@ func (vm *Interpreter) evalStmt(s ast.Stmt, env *Env) (controlFlow, error) {
 		chv, err := vm.evalExpr(st.Chan, env); if err != nil { return controlFlow{}, err }
 		val, err := vm.evalExpr(st.Value, env); if err != nil { return controlFlow{}, err }
 		ch, ok := chv.(*ChannelVal); if !ok || ch == nil { return controlFlow{}, NewRuntimeError("send on non-channel") }
-		ch.C <- val
+		val = vm.valueAs(ch.ElementType, copyValue(val))
+		if _, _, _, err := selectCases([]reflect.SelectCase{{Dir: reflect.SelectSend, Chan: reflect.ValueOf(ch.C), Send: reflect.ValueOf(&val).Elem()}}); err != nil { return controlFlow{}, err }
 		return controlFlow{}, nil
 
 	case *ast.AssignStmt:
 		// Evaluate RHS first
 		rightVals := make([]any, len(st.Rhs))
 
-		// Special case: v, ok := m[k]
+		// Special case: v, ok := m[k] / <-ch / x.(T)
 		if len(st.Lhs) == 2 && len(st.Rhs) == 1 {
-			if ie, ok := st.Rhs[0].(*ast.IndexExpr); ok {
-				mv, err := vm.evalExpr(ie.X, env); if err != nil { return controlFlow{}, err }
-				if m, ok := mv.(*MapVal); ok {
-					key, err := vm.evalExpr(ie.Index, env); if err != nil { return controlFlow{}, err }
-					val, ok2 := m.getByKey(key)
-					rightVals = []any{val, ok2}
-					goto RHS_DONE
-				}
-			}
+			vals, ok, err := vm.evalCommaOk(st.Rhs[0], env); if err != nil { return controlFlow{}, err }
+			if ok { rightVals = vals; goto RHS_DONE }
 		}
 
 		for i, r := range st.Rhs {
-			// Special case: two-value receive v, ok := <-ch
-			if len(st.Lhs) == 2 {
-				if ue, ok := r.(*ast.UnaryExpr); ok && ue.Op == token.ARROW {
-					// two-value receive
-					cv, err := vm.evalExpr(ue.X, env); if err != nil { return controlFlow{}, err }
-					ch, ok := cv.(*ChannelVal); if !ok || ch == nil { return controlFlow{}, NewRuntimeError("receive on non-channel") }
-					v, ok2 := <- ch.C
-					rightVals = []any{v, ok2}
-					goto RHS_DONE
-				}
-			}
-			v, err := vm.evalExpr(r, env); if err != nil { return controlFlow{}, err }
+			// x = c checks the constant c against the declared type of x
+			typ := ""
+			if id, ok := st.Lhs[min(i, len(st.Lhs)-1)].(*ast.Ident); ok && st.Tok == token.ASSIGN { typ = declaredType(id.Name, env) }
+			v, err := vm.evalAs(r, typ, env); if err != nil { return controlFlow{}, err }
 			rightVals[i] = v
 		}
+		// Multi-value call on the right: a, b := f()
+		if len(st.Rhs) == 1 {
+			if tv, ok := rightVals[0].(*TupleVal); ok { rightVals = tv.Values }
+		}
 	RHS_DONE:
+		if len(rightVals) != len(st.Lhs) && (st.Tok == token.DEFINE || st.Tok == token.ASSIGN) {
+			return controlFlow{}, NewRuntimeError(fmt.Sprintf("assignment mismatch: %d variables but %d values", len(st.Lhs), len(rightVals)))
+		}
+		if st.Tok == token.DEFINE { return controlFlow{}, vm.define(st.Lhs, rightVals, env) }
 		// Resolve LHS references
 		leftRefs := make([]Ref, len(st.Lhs))
 		for i, l := range st.Lhs {
@@ -464,14 +462,9 @@ func (vm *Interpreter) evalStmt(s ast.Stmt, env *Env) (controlFlow, error) {
 			leftRefs[i] = ref
 		}
 		switch st.Tok {
-		case token.DEFINE:
-			for i, l := range st.Lhs {
-				if id, ok := l.(*ast.Ident); ok { if id.Name == "_" { continue }; var v any; if len(rightVals) == 1 { v = rightVals[0] } else { v = rightVals[i] }; vm.declare(id.Name, v, env) } else { return controlFlow{}, NewRuntimeError("invalid := lhs") }
-			}
 		case token.ASSIGN:
 			for i, ref := range leftRefs {
-				var v any; if len(rightVals) == 1 { v = rightVals[0] } else { v = rightVals[i] }
-				if err := ref.Set(v); err != nil { return controlFlow{}, err }
+				if err := ref.Set(rightVals[i]); err != nil { return controlFlow{}, err }
 			}
 		default:
 			// augmented assignments supported via applyBinaryOp
@@ -499,91 +492,126 @@ func (vm *Interpreter) evalStmt(s ast.Stmt, env *Env) (controlFlow, error) {
 
 	case *ast.IncDecStmt:
 		ref, err := vm.resolveRef(st.X, env); if err != nil { return controlFlow{}, err }
-		cur := ToInt(ref.Get()); if st.Tok == token.INC { ref.Set(cur+1) } else { ref.Set(cur-1) }
-		return controlFlow{}, nil
+		op := token.ADD; if st.Tok == token.DEC { op = token.SUB }
+		v, err := vm.applyBinaryOp(op, ref.Get(), 1); if err != nil { return controlFlow{}, err }
+		return controlFlow{}, ref.Set(v)
 
 	case *ast.DeclStmt:
 		decl := st.Decl.(*ast.GenDecl)
 		switch decl.Tok {
-		case token.VAR, token.CONST:
+		case token.CONST:
+			if err := vm.declareConsts(decl, env); err != nil { return controlFlow{}, err }
+		case token.VAR:
 			for _, sp := range decl.Specs {
-				vs := sp.(*ast.ValueSpec)
-				for i, n := range vs.Names {
-					if n.Name == "_" { continue }
-					var val any
-					if i < len(vs.Values) { v, err := vm.evalExpr(vs.Values[i], env); if err != nil { return controlFlow{}, err }; val = v } else { val = zeroValue(typeString(vs.Type)) }
-					vm.declare(n.Name, val, env)
-				}
+				if err := vm.declareValueSpec(sp.(*ast.ValueSpec), env); err != nil { return controlFlow{}, err }
 			}
+		case token.TYPE:
+			for _, sp := range decl.Specs { vm.declareType(sp.(*ast.TypeSpec)) }
 		}
 		return controlFlow{}, nil
 
 	case *ast.BlockStmt:
-		local := NewEnv(env)
-		for _, s2 := range st.List {
-			c, err := vm.evalStmt(s2, local); if err != nil { return controlFlow{}, err }
-			switch c.kind {
-			case controlReturn, controlBreak, controlContinue:
-				return c, nil
-			}
-		}
-		return controlFlow{}, nil
+		return vm.execList(st.List, NewEnv(env))
+
+	case *ast.LabeledStmt:
+		return vm.execStmt(st.Stmt, st.Label.Name, env)
 
 	case *ast.IfStmt:
-		if st.Init != nil { if _, err := vm.evalStmt(st.Init, env); err != nil { return controlFlow{}, err } }
-		cond, err := vm.evalExpr(st.Cond, env); if err != nil { return controlFlow{}, err }
-		if ToBool(cond) { return vm.evalStmt(st.Body, env) } else if st.Else != nil { return vm.evalStmt(st.Else, env) }
+		local := NewEnv(env)
+		if st.Init != nil { if _, err := vm.evalStmt(st.Init, local); err != nil { return controlFlow{}, err } }
+		cond, err := vm.evalExpr(st.Cond, local); if err != nil { return controlFlow{}, err }
+		if ToBool(cond) { return vm.evalStmt(st.Body, local) } else if st.Else != nil { return vm.evalStmt(st.Else, local) }
 		return controlFlow{}, nil
 
 	case *ast.ForStmt:
 		local := NewEnv(env)
 		if st.Init != nil { if _, err := vm.evalStmt(st.Init, local); err != nil { return controlFlow{}, err } }
+		// Variables declared by the init statement are per iteration: each
+		// iteration after the first starts from a copy of the previous one.
+		init, _ := st.Init.(*ast.AssignStmt); perIter := init != nil && init.Tok == token.DEFINE
 		for {
 			cond := true
 			if st.Cond != nil { v, err := vm.evalExpr(st.Cond, local); if err != nil { return controlFlow{}, err }; cond = ToBool(v) }
 			if !cond { break }
 			c, err := vm.evalStmt(st.Body, local); if err != nil { return controlFlow{}, err }
-			switch c.kind {
-			case controlBreak: return controlFlow{}, nil
-			case controlReturn: return c, nil
-			case controlContinue: /* continue */ }
+			if stop, out := loopControl(c, label); stop { return out, nil }
+			if perIter {
+				next := &Env{Vars: make(map[string]any, len(local.Vars)), Types: maps.Clone(local.Types), Parent: env}
+				for k, v := range local.Vars { next.Vars[k] = copyValue(v) }
+				local = next
+			}
 			if st.Post != nil { if _, err := vm.evalStmt(st.Post, local); err != nil { return controlFlow{}, err } }
 		}
 		return controlFlow{}, nil
 
 	case *ast.RangeStmt:
-		local := NewEnv(env)
-		x, err := vm.evalExpr(st.X, local); if err != nil { return controlFlow{}, err }
+		x, err := vm.evalExpr(st.X, env); if err != nil { return controlFlow{}, err }
+		named, _ := x.(*NamedVal)
+		x = underlying(x)
+		// An array is ranged over as a copy, a pointer to an array in place.
+		if a, ok := x.(*ArrayVal); ok { x = &SliceVal{ElementType: a.ElementType, Data: copyValue(a).(*ArrayVal).Data} }
+		if a, ok := arrayOf(x).(*ArrayVal); ok { x = &SliceVal{ElementType: a.ElementType, Data: a.Data} }
+		// iterate runs the body for one key and value. With :=, each
+		// iteration declares fresh variables, so closures capture their own.
+		vars := [2]ast.Expr{st.Key, st.Value}
+		iterate := func(key, val any) (bool, controlFlow, error) {
+			iter := env
+			if st.Tok == token.DEFINE { iter = NewEnv(env) }
+			for i, v := range [2]any{key, val} {
+				if vars[i] == nil { continue }
+				if id, ok := vars[i].(*ast.Ident); ok && id.Name == "_" { continue }
+				if st.Tok == token.DEFINE { vm.declare(vars[i].(*ast.Ident).Name, v, iter); continue }
+				ref, err := vm.resolveRef(vars[i], env); if err != nil { return true, controlFlow{}, err }
+				if err := ref.Set(v); err != nil { return true, controlFlow{}, err }
+			}
+			c, err := vm.evalStmt(st.Body, iter); if err != nil { return true, controlFlow{}, err }
+			stop, out := loopControl(c, label)
+			return stop, out, nil
+		}
 		switch s := x.(type) {
 		case *SliceVal:
 			for i := 0; i < len(s.Data); i++ {
-				if st.Key != nil { if id, ok := st.Key.(*ast.Ident); ok && id.Name != "_" { vm.set(id.Name, i, local) } }
-				if st.Value != nil { if id, ok := st.Value.(*ast.Ident); ok && id.Name != "_" { vm.set(id.Name, s.Data[i], local) } }
-				c, err := vm.evalStmt(st.Body, local); if err != nil { return controlFlow{}, err }
-				switch c.kind { case controlBreak: return controlFlow{}, nil; case controlReturn: return c, nil; case controlContinue: }
+				if stop, out, err := iterate(i, s.Data[i]); stop { return out, err }
 			}
 		case *MapVal:
 			for _, hk := range keysOfMap(s) {
-				key := s.Keys[hk]; val := s.Data[hk]
-				if st.Key != nil { if id, ok := st.Key.(*ast.Ident); ok && id.Name != "_" { vm.set(id.Name, key, local) } }
-				if st.Value != nil { if id, ok := st.Value.(*ast.Ident); ok && id.Name != "_" { vm.set(id.Name, val, local) } }
-				c, err := vm.evalStmt(st.Body, local); if err != nil { return controlFlow{}, err }
-				switch c.kind { case controlBreak: return controlFlow{}, nil; case controlReturn: return c, nil; case controlContinue: }
+				if stop, out, err := iterate(s.Keys[hk], s.Data[hk]); stop { return out, err }
 			}
 		case string:
-			for i := 0; i < len(s); i++ {
-				if st.Key != nil { if id, ok := st.Key.(*ast.Ident); ok && id.Name != "_" { vm.set(id.Name, i, local) } }
-				if st.Value != nil { if id, ok := st.Value.(*ast.Ident); ok && id.Name != "_" { vm.set(id.Name, int(s[i]), local) } }
-				c, err := vm.evalStmt(st.Body, local); if err != nil { return controlFlow{}, err }
-				switch c.kind { case controlBreak: return controlFlow{}, nil; case controlReturn: return c, nil; case controlContinue: }
+			// A string ranges over the runes of its UTF-8 encoding; i is a byte offset.
+			for i, r := range s {
+				if stop, out, err := iterate(i, r); stop { return out, err }
 			}
 		case *ChannelVal:
 			for v := range s.C {
-				if st.Key != nil { if id, ok := st.Key.(*ast.Ident); ok && id.Name != "_" { vm.set(id.Name, v, local) } }
-				c, err := vm.evalStmt(st.Body, local); if err != nil { return controlFlow{}, err }
-				switch c.kind { case controlBreak: return controlFlow{}, nil; case controlReturn: return c, nil; case controlContinue: }
+				if stop, out, err := iterate(v, nil); stop { return out, err }
 			}
+		case *Function:
+			// A range function calls yield for each element. yield returns
+			// false once the loop is left, and must not be called again.
+			var stop, done bool; var out controlFlow; var berr error
+			yield := &Function{Name: "yield", Native: func(args []any) (any, error) {
+				if done { return nil, &panicError{value: "runtime error: range function continued iteration after function for loop body returned false"} }
+				var k, v any
+				if len(args) > 0 { k = args[0] }
+				if len(args) > 1 { v = args[1] }
+				stop, out, berr = iterate(k, v)
+				done = stop; return !stop, berr
+			}}
+			_, err := vm.callFunction(s, env, nil, []any{yield})
+			if berr != nil { err = berr }
+			if err != nil { return controlFlow{}, err }
+			if stop { return out, nil }
 		default:
+			// range n counts from 0 to n-1 in the type of n
+			if t := numericType(x); isIntegerType(t) {
+				for i, n := 0, ToInt(x); i < n; i++ {
+					var k any = convertNumber(t, i)
+					if named != nil { k = &NamedVal{TypeName: named.TypeName, Value: k} }
+					if stop, out, err := iterate(k, nil); stop { return out, err }
+				}
+				break
+			}
 			return controlFlow{}, NewRuntimeError("range over unsupported type")
 		}
 		return controlFlow{}, nil
@@ -593,35 +621,40 @@ func (vm *Interpreter) evalStmt(s ast.Stmt, env *Env) (controlFlow, error) {
 		if st.Init != nil { if _, err := vm.evalStmt(st.Init, local); err != nil { return controlFlow{}, err } }
 		var tag any; var err error
 		if st.Tag != nil { tag, err = vm.evalExpr(st.Tag, local); if err != nil { return controlFlow{}, err } }
-		matched := false
-		for _, clause := range st.Body.List {
+		// The default clause only runs once no case matches, wherever it is.
+		run, def := -1, -1
+		for i, clause := range st.Body.List {
 			cc := clause.(*ast.CaseClause)
-			if cc.List == nil {
-				if !matched {
-					return vm.evalStmt(&ast.BlockStmt{List: cc.Body}, local)
-				}
-				continue
-			}
-			if matched { continue }
+			if cc.List == nil { def = i; continue }
 			for _, ce := range cc.List {
-				val, err := vm.evalExpr(ce, local); if err != nil { return controlFlow{}, err }
-				if st.Tag == nil {
-					if ToBool(val) { matched = true; break }
-				} else {
-					if equals(tag, val) { matched = true; break }
-				}
+				// untyped constant cases take the defined type of the tag
+				typ := ""; if n, ok := tag.(*NamedVal); ok && !vm.interfaceTyped(st.Tag, local) { typ = n.TypeName }
+				val, err := vm.evalAs(ce, typ, local); if err != nil { return controlFlow{}, err }
+				if st.Tag == nil && ToBool(val) || st.Tag != nil && equals(tag, val) { run = i; break }
 			}
-			if matched { return vm.evalStmt(&ast.BlockStmt{List: cc.Body}, local) }
+			if run >= 0 { break }
+		}
+		if run < 0 { run = def }
+		for ; run >= 0 && run < len(st.Body.List); run++ {
+			c, err := vm.evalStmt(&ast.BlockStmt{List: st.Body.List[run].(*ast.CaseClause).Body}, local); if err != nil { return controlFlow{}, err }
+			if c.kind != controlFallthrough { return breakOut(c, label), nil }
 		}
 		return controlFlow{}, nil
 
+	case *ast.SelectStmt:
+		return vm.evalSelect(st, label, env)
+
+	case *ast.TypeSwitchStmt:
+		return vm.evalTypeSwitch(st, label, env)
+
 	case *ast.DeferStmt:
 		// Capture callable and its arguments NOW, but execute on function return/panic.
 		fn, recv, args, err := vm.prepareCall(st.Call, env)
 		if err != nil { return controlFlow{}, err }
-		frame := vm.currentFrame(); if frame == nil { return controlFlow{}, NewRuntimeError("defer outside of function") }
-		frame.defers = append(frame.defers, func() {
-			_, _ = vm.callFunction(fn, env, recv, args)
+		frame := frameOf(env); if frame == nil { return controlFlow{}, NewRuntimeError("defer outside of function") }
+		frame.defers = append(frame.defers, func() error {
+			_, err := vm.invoke(fn, recv, args, frame)
+			return err
 		})
 		return controlFlow{}, nil
 
@@ -632,14 +665,32 @@ func (vm *Interpreter) evalStmt(s ast.Stmt, env *Env) (controlFlow, error) {
 		return controlFlow{}, nil
 
 	case *ast.ReturnStmt:
+		if scope := frameScope(env); scope != nil && len(scope.frame.results) > 0 {
+			// With named results, return assigns them; the caller reads them once defers ran.
+			names := scope.frame.results
+			vals := make([]any, 0, len(names))
+			for _, r := range st.Results { v, err := vm.evalExpr(r, env); if err != nil { return controlFlow{}, err }; vals = append(vals, v) }
+			if len(vals) == 1 && len(names) > 1 { if t, ok := vals[0].(*TupleVal); ok { vals = t.Values } }
+			if len(vals) > 0 && len(vals) != len(names) { return controlFlow{}, NewRuntimeError(fmt.Sprintf("wrong number of return values: have %d, want %d", len(vals), len(names))) }
+			for i, v := range vals { if err := vm.assign(names[i], v, scope); err != nil { return controlFlow{}, err } }
+			return controlFlow{kind: controlReturn}, nil
+		}
 		if len(st.Results) == 0 { return controlFlow{kind: controlReturn, val: nil}, nil }
-		v, err := vm.evalExpr(st.Results[0], env); if err != nil { return controlFlow{}, err }
-		return controlFlow{kind: controlReturn, val: v}, nil
+		if len(st.Results) == 1 {
+			// return f() passes a multi-value result through unchanged.
+			v, err := vm.evalExpr(st.Results[0], env); if err != nil { return controlFlow{}, err }
+			return controlFlow{kind: controlReturn, val: v}, nil
+		}
+		vals := make([]any, len(st.Results))
+		for i, r := range st.Results { v, err := vm.evalExpr(r, env); if err != nil { return controlFlow{}, err }; vals[i] = v }
+		return controlFlow{kind: controlReturn, val: &TupleVal{Values: vals}}, nil
 
 	case *ast.BranchStmt:
 		switch st.Tok {
-		case token.BREAK:		return controlFlow{kind: controlBreak}, nil
-		case token.CONTINUE:	return controlFlow{kind: controlContinue}, nil
+		case token.BREAK:		return controlFlow{kind: controlBreak, label: labelName(st.Label)}, nil
+		case token.CONTINUE:	return controlFlow{kind: controlContinue, label: labelName(st.Label)}, nil
+		case token.GOTO:		return controlFlow{kind: controlGoto, label: st.Label.Name}, nil
+		case token.FALLTHROUGH:	return controlFlow{kind: controlFallthrough}, nil
 		}
 		return controlFlow{}, nil
 
@@ -650,46 +701,219 @@ func (vm *Interpreter) evalStmt(s ast.Stmt, env *Env) (controlFlow, error) {
 
 func keysOfMap(m *MapVal) []string { out := make([]string, 0, len(m.Keys)); for k := range m.Keys { out = append(out, k) }; return out }
 
+// evalSelect evaluates every channel operand and send value once, in source
+// order, then lets reflect.Select pick among the ready cases. Nil channels
+// become zero reflect.Values, which reflect.Select never chooses.
+func (vm *Interpreter) evalSelect(st *ast.SelectStmt, label string, env *Env) (controlFlow, error) {
+	clauses := make([]*ast.CommClause, 0, len(st.Body.List))
+	cases := make([]reflect.SelectCase, 0, len(st.Body.List))
+	chans := make([]*ChannelVal, 0, len(st.Body.List))
+	for _, c := range st.Body.List {
+		cc := c.(*ast.CommClause)
+		sc := reflect.SelectCase{Dir: reflect.SelectDefault}
+		var ch *ChannelVal
+		if cc.Comm != nil {
+			var chExpr ast.Expr
+			switch comm := cc.Comm.(type) {
+			case *ast.SendStmt:
+				sc.Dir = reflect.SelectSend; chExpr = comm.Chan
+			case *ast.ExprStmt:
+				sc.Dir = reflect.SelectRecv; chExpr = recvOperand(comm.X)
+			case *ast.AssignStmt:
+				sc.Dir = reflect.SelectRecv; if len(comm.Rhs) == 1 { chExpr = recvOperand(comm.Rhs[0]) }
+			}
+			if chExpr == nil { return controlFlow{}, NewRuntimeError("select case must be receive, send or assign recv") }
+			chv, err := vm.evalExpr(chExpr, env); if err != nil { return controlFlow{}, err }
+			if chv != nil {
+				c2, ok := chv.(*ChannelVal); if !ok { return controlFlow{}, NewRuntimeError("select on non-channel") }
+				ch = c2
+			}
+			if ch != nil && ch.C != nil { sc.Chan = reflect.ValueOf(ch.C) }
+			if send, ok := cc.Comm.(*ast.SendStmt); ok {
+				val, err := vm.evalExpr(send.Value, env); if err != nil { return controlFlow{}, err }
+				if ch != nil { val = vm.valueAs(ch.ElementType, copyValue(val)) }
+				sc.Send = reflect.ValueOf(&val).Elem()
+			}
+		}
+		clauses = append(clauses, cc); cases = append(cases, sc); chans = append(chans, ch)
+	}
+
+	chosen, recv, recvOK, err := selectCases(cases); if err != nil { return controlFlow{}, err }
+	cc := clauses[chosen]
+	local := NewEnv(env)
+	if as, ok := cc.Comm.(*ast.AssignStmt); ok {
+		var val any
+		if recvOK { val = recv.Interface() } else { val = vm.zeroValue(chans[chosen].ElementType) }
+		vals := []any{val, recvOK}
+		for i, l := range as.Lhs {
+			if i >= len(vals) { break }
+			if as.Tok == token.DEFINE {
+				if id, ok := l.(*ast.Ident); ok && id.Name != "_" { vm.declare(id.Name, vals[i], local) }
+				continue
+			}
+			ref, err := vm.resolveRef(l, env); if err != nil { return controlFlow{}, err }
+			if err := ref.Set(vals[i]); err != nil { return controlFlow{}, err }
+		}
+	}
+	c, err := vm.evalStmt(&ast.BlockStmt{List: cc.Body}, local); if err != nil { return controlFlow{}, err }
+	return breakOut(c, label), nil
+}
+
+// selectCases runs reflect.Select, turning a send on a closed channel into
+// a panic of the interpreted program.
+func selectCases(cases []reflect.SelectCase) (chosen int, recv reflect.Value, recvOK bool, err error) {
+	defer func() { if r := recover(); r != nil { err = &panicError{value: closedSend} } }()
+	chosen, recv, recvOK = reflect.Select(cases)
+	return
+}
+
+// closedSend is the panic value of a send on a closed channel.
+const closedSend = "send on closed channel"
+
+// evalTypeSwitch runs the first clause whose type list matches the dynamic
+// type of the guard, or the default clause. A bound variable takes the
+// guard's value in every clause.
+func (vm *Interpreter) evalTypeSwitch(st *ast.TypeSwitchStmt, label string, env *Env) (controlFlow, error) {
+	local := NewEnv(env)
+	if st.Init != nil { if _, err := vm.evalStmt(st.Init, local); err != nil { return controlFlow{}, err } }
+	bind := ""; var guard ast.Expr
+	switch a := st.Assign.(type) {
+	case *ast.AssignStmt: bind = a.Lhs[0].(*ast.Ident).Name; guard = a.Rhs[0]
+	case *ast.ExprStmt: guard = a.X
+	}
+	ta, ok := guard.(*ast.TypeAssertExpr); if !ok { return controlFlow{}, NewRuntimeError("invalid type switch guard") }
+	v, err := vm.evalExpr(ta.X, local); if err != nil { return controlFlow{}, err }
+
+	var chosen, def *ast.CaseClause
+	for _, clause := range st.Body.List {
+		cc := clause.(*ast.CaseClause)
+		if cc.List == nil { def = cc; continue }
+		if vm.typeCaseMatches(v, cc.List, local) { chosen = cc; break }
+	}
+	if chosen == nil { chosen = def }
+	if chosen == nil { return controlFlow{}, nil }
+	body := NewEnv(local)
+	if bind != "" && bind != "_" { vm.declare(bind, v, body) }
+	c, err := vm.evalStmt(&ast.BlockStmt{List: chosen.Body}, body); if err != nil { return controlFlow{}, err }
+	return breakOut(c, label), nil
+}
+
+func (vm *Interpreter) typeCaseMatches(v any, list []ast.Expr, env *Env) bool {
+	for _, te := range list {
+		if id, ok := te.(*ast.Ident); ok && id.Name == "nil" {
+			if v == nil { return true }
+			continue
+		}
+		if vm.typeMatches(v, vm.resolveType(typeString(te), env)) { return true }
+	}
+	return false
+}
+
+// evalCommaOk evaluates the two-value forms m[k], <-ch and x.(T). The
+// second result reports whether e was one of those forms at all.
+func (vm *Interpreter) evalCommaOk(e ast.Expr, env *Env) ([]any, bool, error) {
+	switch ex := e.(type) {
+	case *ast.IndexExpr:
+		mv, err := vm.evalExpr(ex.X, env); if err != nil { return nil, false, err }
+		m, ok := underlying(mv).(*MapVal); if !ok { return nil, false, nil }
+		key, err := vm.evalExpr(ex.Index, env); if err != nil { return nil, false, err }
+		val, found := m.getByKey(vm.valueAs(m.KeyType, key))
+		if !found { val = vm.zeroValue(m.ElementType) }
+		return []any{val, found}, true, nil
+	case *ast.UnaryExpr:
+		if ex.Op != token.ARROW { return nil, false, nil }
+		cv, err := vm.evalExpr(ex.X, env); if err != nil { return nil, false, err }
+		ch, ok := cv.(*ChannelVal); if !ok || ch == nil { return nil, false, NewRuntimeError("receive on non-channel") }
+		v, ok2 := <- ch.C
+		if !ok2 { v = vm.zeroValue(ch.ElementType) }
+		return []any{v, ok2}, true, nil
+	case *ast.TypeAssertExpr:
+		v, err := vm.evalExpr(ex.X, env); if err != nil { return nil, false, err }
+		typ := vm.resolveType(typeString(ex.Type), env)
+		if vm.typeMatches(v, typ) { return []any{v, true}, true, nil }
+		return []any{vm.zeroValue(typ), false}, true, nil
+	}
+	return nil, false, nil
+}
+
+// recvOperand returns the channel expression of a receive (<-ch), or nil.
+func recvOperand(e ast.Expr) ast.Expr {
+	if ue, ok := e.(*ast.UnaryExpr); ok && ue.Op == token.ARROW { return ue.X }
+	return nil
+}
+
+// define runs a short variable declaration. Names not yet declared in env
+// become new variables, the others are assigned; at least one must be new.
+func (vm *Interpreter) define(lhs []ast.Expr, vals []any, env *Env) error {
+	fresh := false
+	seen := map[string]bool{}
+	for _, l := range lhs {
+		id, ok := l.(*ast.Ident); if !ok { return NewRuntimeError("non-name on left side of :=") }
+		if id.Name == "_" { continue }
+		if seen[id.Name] { return NewRuntimeError(id.Name + " repeated on left side of :=") }
+		seen[id.Name] = true
+		if _, ok := env.Consts[id.Name]; ok { return NewRuntimeError(id.Name + " redeclared in this block") }
+		if _, ok := env.Vars[id.Name]; !ok { fresh = true }
+	}
+	if !fresh { return NewRuntimeError("no new variables on left side of :=") }
+	for i, l := range lhs {
+		name := l.(*ast.Ident).Name
+		if _, ok := env.Vars[name]; ok || name == "_" {
+			if err := vm.assign(name, vals[i], env); err != nil { return err }
+			continue
+		}
+		vm.declare(name, vals[i], env)
+	}
+	return nil
+}
+
 func (vm *Interpreter) resolveRef(l ast.Expr, env *Env) (Ref, error) {
 	switch ee := l.(type) {
 	case *ast.Ident:
+		if c, ok := lookupConst(ee.Name, env); ok { return nil, NewRuntimeError(fmt.Sprintf("cannot assign to %s (constant %s of type %s)", ee.Name, c.val, c.typ)) }
 		return &varRef{vm: vm, env: env, name: ee.Name}, nil
 	case *ast.IndexExpr:
 		x, err := vm.evalExpr(ee.X, env); if err != nil { return nil, err }
 		i, err := vm.evalExpr(ee.Index, env); if err != nil { return nil, err }
-		switch s := x.(type) {
+		switch s := arrayOf(x).(type) {
 		case *SliceVal:
-			ii := ToInt(i); if ii < 0 || ii >= len(s.Data) { return nil, NewRuntimeError("index out of range") }
-			return &sliceIndexRef{s: s, i: ii}, nil
+			ii := ToInt(i); if ii < 0 || ii >= len(s.Data) { return nil, indexOutOfRange(ii, len(s.Data)) }
+			return &sliceIndexRef{vm: vm, s: s, i: ii}, nil
+		case *ArrayVal:
+			ii := ToInt(i); if ii < 0 || ii >= len(s.Data) { return nil, indexOutOfRange(ii, len(s.Data)) }
+			return &arrayIndexRef{vm: vm, a: s, i: ii}, nil
 		case *MapVal:
-			return &mapIndexRef{m: s, k: i}, nil
+			return &mapIndexRef{vm: vm, m: s, k: vm.valueAs(s.KeyType, i)}, nil
 		default:
 			return nil, NewRuntimeError("index assign unsupported")
 		}
 	case *ast.SelectorExpr:
 		recv, err := vm.evalExpr(ee.X, env); if err != nil { return nil, err }
+		if p, ok := recv.(*PointerVal); ok { recv, err = vm.deref(p); if err != nil { return nil, err } }
 		sv, ok := recv.(*StructVal); if !ok { return nil, NewRuntimeError("selector assign unsupported") }
-		return &fieldRef{s: sv, name: ee.Sel.Name}, nil
+		return &fieldRef{vm: vm, s: vm.fieldHolder(sv, ee.Sel.Name), name: ee.Sel.Name}, nil
+	case *ast.StarExpr:
+		v, err := vm.evalExpr(ee.X, env); if err != nil { return nil, err }
+		p, ok := v.(*PointerVal); if !ok { return nil, NewRuntimeError("invalid indirect of non-pointer") }
+		if p.Ref == nil { return nil, &panicError{value: nilDeref} }
+		return p.Ref, nil
+	case *ast.ParenExpr:
+		return vm.resolveRef(ee.X, env)
 	default:
 		return nil, NewRuntimeError("invalid lvalue")
 	}
 }
 
 func (vm *Interpreter) callFunction(fn *Function, env *Env, recv *any, args []any) (ret any, err error) {
-	// Run defers in LIFO order on exit; also handle panic unwinding.
-	frame := vm.pushFrame()
-	defer func() {
-		// Execute defers in reverse order
-		for i := len(frame.defers)-1; i >= 0; i-- { frame.defers[i]() }
-		vm.popFrame()
-		if r := recover(); r != nil {
-			if pe, ok := r.(*panicError); ok {
-				// Convert to error so callers can see panic
-				err = pe
-			}
-		}
-	}()
+	return vm.invoke(fn, recv, args, nil)
+}
 
+// invoke runs fn in a new call frame. deferredBy is the frame whose deferred
+// calls are being run, if this is one of them; recover consults it.
+func (vm *Interpreter) invoke(fn *Function, recv *any, args []any, deferredBy *callFrame) (ret any, err error) {
+	var spread *spreadArg
+	if n := len(args); n > 0 { if sa, ok := args[n-1].(*spreadArg); ok { spread = sa; args = args[:n-1:n-1] } }
+	if spread != nil && (fn.Native != nil || !fn.IsVariadic) { args = append(args, sliceData(spread.s)...); spread = nil }
 	// Native function?
 	if fn.Native != nil {
 		var a []any
@@ -700,38 +924,132 @@ func (vm *Interpreter) callFunction(fn *Function, env *Env, recv *any, args []an
 
 	// User-defined function
 	local := NewEnv(fn.Env)
+	frame := &callFrame{deferredBy: deferredBy, results: fn.ResultNames}
+	local.frame = frame
 	argIndex := 0
 	if fn.RecvName != "" && recv != nil { vm.declare(fn.RecvName, *recv, local) }
+	// Generic functions bind their type parameters for the body.
+	var targs map[string]string
+	if len(fn.TypeParams) > 0 {
+		targsOf := args; if spread != nil { targsOf = append(args, spread) }
+		ts, err := vm.typeArgsFor(fn, recv, targsOf); if err != nil { return nil, err }
+		targs = map[string]string{}
+		for i, p := range fn.TypeParams { targs[p] = ts[i]; vm.declare(p, &typeBinding{typ: ts[i]}, local) }
+	}
+	// bind declares parameter i, checking the argument against its declared type.
+	bind := func(i int, v any) error {
+		if i < len(fn.ParamTypes) { return vm.declareTyped(fn.Params[i], substTypes(fn.ParamTypes[i], targs), v, local) }
+		vm.declare(fn.Params[i], v, local); return nil
+	}
+	// Named results start out as zero values.
+	for i, n := range fn.ResultNames {
+		t := substTypes(fn.ResultTypes[i], targs)
+		if err := vm.declareTyped(n, t, vm.zeroValue(t), local); err != nil { return nil, err }
+	}
 	if fn.IsVariadic && len(fn.Params) > 0 {
 		// All args before the last param are regular; the rest packed into a slice.
 		for i := 0; i < len(fn.Params)-1; i++ {
-			if argIndex >= len(args) { vm.declare(fn.Params[i], nil, local) } else { vm.declare(fn.Params[i], args[argIndex], local) }
+			var v any; if argIndex < len(args) { v = args[argIndex] }
+			if err := bind(i, v); err != nil { return nil, err }
 			argIndex++
 		}
-		var rest []any
-		for argIndex < len(args) { rest = append(rest, args[argIndex]); argIndex++ }
-		vm.declare(fn.Params[len(fn.Params)-1], &SliceVal{ElementType: "any", Data: rest}, local)
+		// The rest are packed into a []T, unless the caller passed s... as is.
+		last := len(fn.Params)-1
+		elem := "any"; if last < len(fn.ParamTypes) { elem = strings.TrimPrefix(substTypes(fn.ParamTypes[last], targs), "...") }
+		rest := &SliceVal{ElementType: elem}
+		if spread != nil {
+			if s, ok := underlying(spread.s).(*SliceVal); ok { rest = s }
+		} else {
+			for ; argIndex < len(args); argIndex++ {
+				v, err := vm.convertTo(elem, args[argIndex]); if err != nil { return nil, err }
+				rest.Data = append(rest.Data, v)
+			}
+		}
+		if err := vm.declareTyped(fn.Params[last], "[]"+elem, rest, local); err != nil { return nil, err }
 	} else {
-		for _, p := range fn.Params {
-			if argIndex >= len(args) { vm.declare(p, nil, local) } else { vm.declare(p, args[argIndex], local) }
+		for i := range fn.Params {
+			var v any; if argIndex < len(args) { v = args[argIndex] }
+			if err := bind(i, v); err != nil { return nil, err }
 			argIndex++
 		}
 	}
 
-	for _, st := range fn.Body.(*ast.BlockStmt).List {
-		c, err := vm.evalStmt(st, local); if err != nil {
-			// If err is panicError, re-panic to trigger unwinding of outer defers.
-			if _, ok := err.(*panicError); ok { panic(err) }
-			return nil, err
-		}
-		switch c.kind {
-		case controlReturn: return c.val, nil
-		case controlBreak, controlContinue: return nil, NewRuntimeError("break/continue outside loop")
-		}
+	ret, err = vm.execBody(fn, local)
+	if err == nil { ret = vm.typedResults(fn, ret, targs) }
+	if pe, ok := err.(*panicError); ok { frame.panicking = pe; err = nil }
+	// Deferred calls run in LIFO order, also while panicking; a panic in one
+	// of them replaces the current one.
+	for i := len(frame.defers)-1; i >= 0; i-- {
+		derr := frame.defers[i]()
+		if pe, ok := derr.(*panicError); ok { frame.panicking = pe } else if derr != nil && err == nil { err = derr }
+	}
+	if frame.panicking != nil { return nil, frame.panicking }
+	// Deferred calls may have changed named results after the return.
+	if len(fn.ResultNames) > 0 { return vm.namedResults(fn, local), err }
+	if frame.recovered { return vm.zeroResults(fn), err }
+	return ret, err
+}
+
+// execBody runs the statements of a user function.
+func (vm *Interpreter) execBody(fn *Function, local *Env) (any, error) {
+	c, err := vm.execList(fn.Body.(*ast.BlockStmt).List, local); if err != nil { return nil, err }
+	switch c.kind {
+	case controlReturn: return c.val, nil
+	case controlBreak, controlContinue: return nil, NewRuntimeError("break/continue outside loop")
+	case controlGoto: return nil, NewRuntimeError("label " + c.label + " not defined")
 	}
 	return nil, nil
 }
 
+// execList runs a statement list in env. A goto resumes at its label when
+// the label belongs to this list; otherwise it propagates outward.
+func (vm *Interpreter) execList(list []ast.Stmt, env *Env) (controlFlow, error) {
+	for i := 0; i < len(list); i++ {
+		c, err := vm.evalStmt(list[i], env); if err != nil { return controlFlow{}, err }
+		if c.kind == controlNone { continue }
+		if c.kind != controlGoto { return c, nil }
+		j := labelIndex(list, c.label); if j < 0 { return c, nil }
+		i = j - 1
+	}
+	return controlFlow{}, nil
+}
+
+func labelIndex(list []ast.Stmt, name string) int {
+	for i, s := range list { if ls, ok := s.(*ast.LabeledStmt); ok && ls.Label.Name == name { return i } }
+	return -1
+}
+
+func labelName(id *ast.Ident) string { if id == nil { return "" }; return id.Name }
+
+// typedResults converts returned values to the declared result types, so
+// that return 1 in a func() uint8 yields a uint8.
+func (vm *Interpreter) typedResults(fn *Function, ret any, targs map[string]string) any {
+	if len(fn.ResultTypes) == 1 { return vm.valueAs(substTypes(fn.ResultTypes[0], targs), ret) }
+	if t, ok := ret.(*TupleVal); ok && len(t.Values) == len(fn.ResultTypes) {
+		for i, v := range t.Values { t.Values[i] = vm.valueAs(substTypes(fn.ResultTypes[i], targs), v) }
+	}
+	return ret
+}
+
+// zeroResults is what a function returns after recovering from a panic.
+func (vm *Interpreter) zeroResults(fn *Function) any {
+	switch len(fn.ResultTypes) {
+	case 0: return nil
+	case 1: return vm.zeroValue(fn.ResultTypes[0])
+	}
+	vals := make([]any, len(fn.ResultTypes))
+	for i, t := range fn.ResultTypes { vals[i] = vm.zeroValue(t) }
+	return &TupleVal{Values: vals}
+}
+
+// namedResults reads the final values of fn's named results from its scope.
+func (vm *Interpreter) namedResults(fn *Function, local *Env) any {
+	if len(fn.ResultNames) == 1 { return local.Vars[fn.ResultNames[0]] }
+	vals := make([]any, len(fn.ResultNames))
+	for i, n := range fn.ResultNames { vals[i] = local.Vars[n] }
+	return &TupleVal{Values: vals}
+}
+
 // prepareCall evaluates a CallExpr into callee and concrete argument list without invoking it.
 func (vm *Interpreter) prepareCall(call *ast.CallExpr, env *Env) (*Function, *any, []any, error) {
 	// Method / package / function cases similar to evalExpr(CallExpr) but do not call.
@@ -741,27 +1059,467 @@ func (vm *Interpreter) prepareCall(call *ast.CallExpr, env *Env) (*Function, *an
 			if p, ok := vm.globals.Vars[pid.Name].(*Package); ok {
 				m, ok2 := vm.resolvePackageSelector(p, sel.Sel.Name); if !ok2 { return nil, nil, nil, NewRuntimeError("unknown package member") }
 				fn, ok3 := m.(*Function); if !ok3 { return nil, nil, nil, NewRuntimeError("member not function") }
-				var args []any; for _, a := range call.Args { v, err := vm.evalExpr(a, env); if err != nil { return nil, nil, nil, err }; args = append(args, v) }
+				args, err := vm.evalArgs(call, env); if err != nil { return nil, nil, nil, err }
 				return fn, nil, args, nil
 			}
 		}
-		// Method call on struct
-		recv, err := vm.evalExpr(sel.X, env); if err != nil { return nil, nil, nil, err }
-		recvType := typeOfValue(vm, recv); td := vm.types[recvType]; if td == nil || td.Methods == nil { return nil, nil, nil, NewRuntimeError("unknown method") }
-		fn := td.Methods[sel.Sel.Name]; if fn == nil { return nil, nil, nil, NewRuntimeError("method not found") }
-		var args []any; for _, a := range call.Args { v, err := vm.evalExpr(a, env); if err != nil { return nil, nil, nil, err }; args = append(args, v) }
-		return fn, &recv, args, nil
+		// Method call, or a call of a func-valued field
+		fn, recv, err := vm.selectCallee(sel, env); if err != nil { return nil, nil, nil, err }
+		args, err := vm.evalArgs(call, env); if err != nil { return nil, nil, nil, err }
+		return fn, recv, args, nil
 	}
 
 	callee, err := vm.evalExpr(call.Fun, env); if err != nil { return nil, nil, nil, err }
-	fn, ok := callee.(*Function); if !ok { return nil, nil, nil, NewRuntimeError("not a function") }
-	var args []any; for _, a := range call.Args { v, err := vm.evalExpr(a, env); if err != nil { return nil, nil, nil, err }; args = append(args, v) }
+	fn, ok := underlying(callee).(*Function); if !ok { return nil, nil, nil, NewRuntimeError("not a function") }
+	args, err := vm.evalArgs(call, env); if err != nil { return nil, nil, nil, err }
 	return fn, nil, args, nil
 }
 
+// evalArgs evaluates call arguments. A single argument that is itself a
+// multi-value call is spread into the argument list, as in f(g()). The
+// final argument of f(s...) is marked as a spreadArg.
+func (vm *Interpreter) evalArgs(call *ast.CallExpr, env *Env) ([]any, error) {
+	args := make([]any, 0, len(call.Args))
+	for _, a := range call.Args {
+		v, err := vm.evalExpr(a, env); if err != nil { return nil, err }
+		args = append(args, v)
+	}
+	if len(args) == 1 {
+		if tv, ok := args[0].(*TupleVal); ok { return append([]any(nil), tv.Values...), nil }
+	}
+	if call.Ellipsis.IsValid() && len(args) > 0 { args[len(args)-1] = &spreadArg{args[len(args)-1]} }
+	return args, nil
+}
+
+// spreadArg is the slice s passed as f(s...). A variadic function receives
+// s itself as its final parameter; natives get its elements as arguments.
+type spreadArg struct{ s any }
+
+// nilDeref is the panic value of a nil pointer dereference.
+const nilDeref = "runtime error: invalid memory address or nil pointer dereference"
+
+// indexOutOfRange is the panic raised by an out-of-bounds index.
+func indexOutOfRange(i, n int) error {
+	return &panicError{value: fmt.Sprintf("runtime error: index out of range [%d] with length %d", i, n)}
+}
+
+// addressOf evaluates &x. Variables, fields, slice elements and *p are
+// addressed in place; composite literals and other values get a new cell.
+func (vm *Interpreter) addressOf(x ast.Expr, env *Env) (*PointerVal, error) {
+	switch ee := x.(type) {
+	case *ast.Ident, *ast.SelectorExpr, *ast.IndexExpr, *ast.StarExpr:
+		ref, err := vm.resolveRef(ee, env); if err != nil { return nil, err }
+		return &PointerVal{ElementType: typeOfValue(vm, ref.Get()), Ref: ref}, nil
+	case *ast.ParenExpr:
+		return vm.addressOf(ee.X, env)
+	}
+	v, err := vm.evalExpr(x, env); if err != nil { return nil, err }
+	return &PointerVal{ElementType: typeOfValue(vm, v), Ref: &cellRef{v: v}}, nil
+}
+
+// deref evaluates *p, panicking on a nil pointer like Go does.
+func (vm *Interpreter) deref(v any) (any, error) {
+	p, ok := v.(*PointerVal); if !ok { return nil, NewRuntimeError("invalid indirect of " + typeOfValue(vm, v)) }
+	if p.Ref == nil { return nil, &panicError{value: nilDeref} }
+	return p.Ref.Get(), nil
+}
+
+// methodReceiver adapts the receiver operand x (evaluated to recv) to the
+// receiver kind of fn: pointer methods get the operand's address, value
+// methods get a copy of the pointee. Native methods see the struct itself.
+func (vm *Interpreter) methodReceiver(fn *Function, x ast.Expr, recv any, env *Env) (any, error) {
+	p, isPtr := recv.(*PointerVal)
+	switch {
+	case fn.RecvPtr && !isPtr:
+		return vm.addressOf(x, env)
+	case !fn.RecvPtr && isPtr:
+		v, err := vm.deref(p); if err != nil { return nil, err }
+		if fn.Native != nil { return v, nil }
+		return copyValue(v), nil
+	case !fn.RecvPtr && fn.Native == nil:
+		return copyValue(recv), nil
+	}
+	return recv, nil
+}
+
+// selectMethod looks up method name on recv, the value of x, and adapts the
+// receiver to it. A method promoted from an embedded field is called on
+// that field.
+func (vm *Interpreter) selectMethod(x ast.Expr, recv any, name string, env *Env) (*Function, any, error) {
+	fn, holder, err := vm.methodOf(recv, name); if err != nil { return nil, nil, err }
+	if holder == nil { r, err := vm.methodReceiver(fn, x, recv, env); return fn, r, err }
+	v := holder.Get()
+	if _, isPtr := v.(*PointerVal); fn.RecvPtr && !isPtr { return fn, &PointerVal{ElementType: typeOfValue(vm, v), Ref: holder}, nil }
+	r, err := vm.methodReceiver(fn, nil, v, env)
+	return fn, r, err
+}
+
+// selectCallee resolves the callee of x.f(...): a method of x, with the
+// receiver to call it on, a func-valued field of x or a method expression.
+func (vm *Interpreter) selectCallee(sel *ast.SelectorExpr, env *Env) (*Function, *any, error) {
+	if fn, ok, err := vm.methodExpr(sel, env); ok || err != nil { return fn, nil, err }
+	recv, err := vm.evalExpr(sel.X, env); if err != nil { return nil, nil, err }
+	if sv := structOf(recv); sv != nil {
+		if f, ok := vm.fieldHolder(sv, sel.Sel.Name).Fields[sel.Sel.Name]; ok {
+			if isNil(f) { return nil, nil, &panicError{value: nilDeref} }
+			fn, ok := underlying(f).(*Function); if !ok { return nil, nil, NewRuntimeError(fmt.Sprintf("invalid operation: cannot call non-function %s", sel.Sel.Name)) }
+			return fn, nil, nil
+		}
+	}
+	fn, r, err := vm.selectMethod(sel.X, recv, sel.Sel.Name, env)
+	if err != nil {
+		if isNil(recv) { return nil, nil, &panicError{value: nilDeref} }
+		return nil, nil, err
+	}
+	return fn, &r, nil
+}
+
+// boundMethod is the method value of fn bound to the receiver recv.
+func (vm *Interpreter) boundMethod(fn *Function, recv any, env *Env) *Function {
+	return &Function{Name: fn.Name, ParamTypes: fn.ParamTypes, ResultTypes: fn.ResultTypes, Native: func(args []any) (any, error) {
+		return vm.callFunction(fn, env, &recv, args)
+	}}
+}
+
+// methodExpr evaluates a method expression T.M or (*T).M to a function
+// that takes the receiver as its first argument; ok is false when sel
+// does not denote one.
+func (vm *Interpreter) methodExpr(sel *ast.SelectorExpr, env *Env) (fn *Function, ok bool, err error) {
+	x := sel.X; if p, isParen := x.(*ast.ParenExpr); isParen { x = p.X }
+	ptr := ""
+	if st, isStar := x.(*ast.StarExpr); isStar { x = st.X; ptr = "*" }
+	id, isIdent := x.(*ast.Ident); if !isIdent { return nil, false, nil }
+	if _, isVar := vm.get(id.Name, env); isVar { return nil, false, nil }
+	td := vm.lookupType(id.Name); if td == nil { return nil, false, nil }
+	name, typ := sel.Sel.Name, ptr + vm.unalias(id.Name)
+	if !vm.typeHasMethod(typ, name) && !(td.Kind == "interface" && slices.Contains(td.MethodSet, name)) {
+		if ptr == "" && vm.typeHasMethod("*" + typ, name) {
+			return nil, true, NewRuntimeError(fmt.Sprintf("invalid method expression %s.%s (needs pointer receiver (*%s).%s)", typ, name, typ, name))
+		}
+		return nil, true, NewRuntimeError(fmt.Sprintf("%s.%s undefined (type %s has no method %s)", typ, name, typ, name))
+	}
+	fn = &Function{Name: typ + "." + name, Native: func(args []any) (any, error) {
+		if len(args) == 0 { return nil, NewRuntimeError("not enough arguments in call to " + typ + "." + name) }
+		m, recv, err := vm.selectMethod(nil, args[0], name, env); if err != nil { return nil, err }
+		return vm.callFunction(m, env, &recv, args[1:])
+	}}
+	if m := td.Methods[name]; m != nil { fn.ParamTypes = append([]string{typ}, m.ParamTypes...); fn.ResultTypes = m.ResultTypes }
+	return fn, true, nil
+}
+
+// methodOf finds method name of v, declared on its type or promoted from an
+// embedded field; holder then refers to that field.
+func (vm *Interpreter) methodOf(v any, name string) (fn *Function, holder Ref, err error) {
+	fn, err = vm.findMethod(v, name); if err == nil { return fn, nil, nil }
+	sv := structOf(v); if sv == nil { return nil, nil, err }
+	holder = vm.promoted(sv, func(e any) bool { _, err := vm.findMethod(e, name); return err == nil })
+	if holder == nil { return nil, nil, err }
+	fn, _ = vm.findMethod(holder.Get(), name)
+	return fn, holder, nil
+}
+
+// fieldHolder returns the struct holding field name of sv: sv itself or,
+// for a promoted field, the embedded struct that declares it.
+func (vm *Interpreter) fieldHolder(sv *StructVal, name string) *StructVal {
+	if _, ok := sv.Fields[name]; ok { return sv }
+	ref := vm.promoted(sv, func(e any) bool {
+		s := structOf(e); if s == nil { return false }
+		_, ok := s.Fields[name]; return ok
+	})
+	if ref != nil { return structOf(ref.Get()) }
+	return sv
+}
+
+// promoted searches the embedded fields of sv, shallowest first, for a
+// value has accepts and returns a reference to that field.
+func (vm *Interpreter) promoted(sv *StructVal, has func(any) bool) Ref {
+	for level := []*StructVal{sv}; len(level) > 0; {
+		var next []*StructVal
+		for _, s := range level {
+			td := vm.lookupType(s.TypeName); if td == nil { continue }
+			for _, f := range td.Fields {
+				if !f.Embedded { continue }
+				if has(s.Fields[f.Name]) { return &fieldRef{vm: vm, s: s, name: f.Name} }
+				if e := structOf(s.Fields[f.Name]); e != nil { next = append(next, e) }
+			}
+		}
+		level = next
+	}
+	return nil
+}
+
+// structOf returns the struct v is or points to, or nil.
+func structOf(v any) *StructVal {
+	if p, ok := v.(*PointerVal); ok && p.Ref != nil { v = p.Ref.Get() }
+	sv, _ := v.(*StructVal)
+	return sv
+}
+
+// arrayOf dereferences a pointer to an array, which Go indexes, slices and
+// ranges over like the array itself. Values of defined types yield their
+// underlying value; others are returned unchanged.
+func arrayOf(v any) any {
+	v = underlying(v)
+	if p, ok := v.(*PointerVal); ok && p.Ref != nil {
+		if a, ok := underlying(p.Ref.Get()).(*ArrayVal); ok { return a }
+	}
+	return v
+}
+
+// sliceValue evaluates v[lo:hi]; hi < 0 means len(v).
+func sliceValue(v any, lo, hi int) (any, error) {
+	switch s := v.(type) {
+	case *SliceVal:
+		if hi < 0 || hi > len(s.Data) { hi = len(s.Data) }
+		if lo < 0 || lo > hi { return nil, NewRuntimeError("invalid slice indices") }
+		return &SliceVal{ElementType: s.ElementType, Data: s.Data[lo:hi]}, nil
+	case *ArrayVal:
+		// slicing an array shares its storage
+		if hi < 0 || hi > len(s.Data) { hi = len(s.Data) }
+		if lo < 0 || lo > hi { return nil, NewRuntimeError("invalid slice indices") }
+		return &SliceVal{ElementType: s.ElementType, Data: s.Data[lo:hi]}, nil
+	case string:
+		if hi < 0 || hi > len(s) { hi = len(s) }
+		if lo < 0 || lo > hi { return nil, NewRuntimeError("invalid slice indices") }
+		return s[lo:hi], nil
+	default:	return nil, NewRuntimeError("slice unsupported")
+	}
+}
+
+// evalElements evaluates the elements of a slice or array literal, which may
+// be keyed by index as in [5]int{2: 7}. n is the array length, or -1 when the
+// length follows from the elements.
+func (vm *Interpreter) evalElements(elts []ast.Expr, elem string, n int, env *Env) ([]any, error) {
+	vals := map[int]any{}
+	idx, size := 0, 0
+	for _, elt := range elts {
+		if kv, ok := elt.(*ast.KeyValueExpr); ok {
+			k, err := vm.evalExpr(kv.Key, env); if err != nil { return nil, err }
+			idx = ToInt(k); elt = kv.Value
+		}
+		if n >= 0 && idx >= n { return nil, NewRuntimeError(fmt.Sprintf("index %d out of bounds [0:%d]", idx, n)) }
+		v, err := vm.evalElem(elt, elem, env); if err != nil { return nil, err }
+		vals[idx] = v
+		idx++
+		if idx > size { size = idx }
+	}
+	if n >= 0 { size = n }
+	data := make([]any, size)
+	for i := range data {
+		if v, ok := vals[i]; ok { data[i] = v } else { data[i] = vm.zeroValue(elem) }
+	}
+	return data, nil
+}
+
+// evalComposite builds the value of a composite literal of type typ. Inner
+// literals may elide their type, which then comes from the element, key or
+// field type they initialise.
+func (vm *Interpreter) evalComposite(lit *ast.CompositeLit, typ string, env *Env) (any, error) {
+	if td := vm.lookupType(typ); td != nil && td.Kind == "named" {
+		v, err := vm.evalComposite(lit, vm.underlyingType(typ), env); if err != nil { return nil, err }
+		return &NamedVal{TypeName: typ, Value: v}, nil
+	}
+	if strings.HasPrefix(typ, "[]") {
+		data, err := vm.evalElements(lit.Elts, typ[2:], -1, env); if err != nil { return nil, err }
+		return &SliceVal{ElementType: typ[2:], Data: data}, nil
+	}
+	if strings.HasPrefix(typ, "[...]") {
+		data, err := vm.evalElements(lit.Elts, typ[5:], -1, env); if err != nil { return nil, err }
+		return &ArrayVal{ElementType: typ[5:], Data: data}, nil
+	}
+	if n, elem, ok := vm.arrayType(typ); ok {
+		data, err := vm.evalElements(lit.Elts, elem, n, env); if err != nil { return nil, err }
+		return &ArrayVal{ElementType: elem, Data: data}, nil
+	}
+	if strings.HasPrefix(typ, "map[") {
+		k, v := parseMapType(typ)
+		m := &MapVal{KeyType: k, ElementType: v, Data: map[string]any{}, Keys: map[string]any{}}
+		for _, elt := range lit.Elts {
+			kv, ok := elt.(*ast.KeyValueExpr); if !ok { return nil, NewRuntimeError("missing key in map literal") }
+			key, err := vm.evalElem(kv.Key, k, env); if err != nil { return nil, err }
+			if _, dup := m.getByKey(key); dup && isBasicLit(kv.Key) { return nil, NewRuntimeError(fmt.Sprintf("duplicate key %s in map literal", ToString(key))) }
+			val, err := vm.evalElem(kv.Value, v, env); if err != nil { return nil, err }
+			m.setByKey(key, val)
+		}
+		return m, nil
+	}
+	// Struct literal, keyed or positional (package prefix reduced by typeString)
+	td := vm.lookupType(typ)
+	if td == nil || td.Kind != "struct" {
+		return nil, NewRuntimeError("unknown struct type: " + typ)
+	}
+	if _, targs := splitTypeArgs(typ); len(targs) != len(td.TypeParams) {
+		return nil, NewRuntimeError(fmt.Sprintf("cannot use generic type %s without instantiation", td.Name))
+	}
+	obj := &StructVal{TypeName: typ, Fields: map[string]any{}}
+	for _, f := range td.Fields { obj.Fields[f.Name] = vm.zeroValue(vm.fieldType(typ, f.Name)) }
+	if len(lit.Elts) == 0 { return obj, nil }
+	_, keyed := lit.Elts[0].(*ast.KeyValueExpr)
+	if !keyed && len(lit.Elts) < len(td.Fields) { return nil, NewRuntimeError("too few values in struct literal of type " + typ) }
+	seen := map[string]bool{}
+	for i, elt := range lit.Elts {
+		kv, isKV := elt.(*ast.KeyValueExpr)
+		if isKV != keyed { return nil, NewRuntimeError("mixture of field:value and value elements in struct literal") }
+		var name string
+		if keyed {
+			id, ok := kv.Key.(*ast.Ident); if !ok { return nil, NewRuntimeError("invalid field name in struct literal") }
+			name = id.Name; elt = kv.Value
+			if _, ok := obj.Fields[name]; !ok { return nil, NewRuntimeError(fmt.Sprintf("unknown field %s in struct literal of type %s", name, typ)) }
+			if seen[name] { return nil, NewRuntimeError(fmt.Sprintf("duplicate field name %s in struct literal", name)) }
+			seen[name] = true
+		} else {
+			if i >= len(td.Fields) { return nil, NewRuntimeError("too many values in struct literal of type " + typ) }
+			name = td.Fields[i].Name
+		}
+		val, err := vm.evalElem(elt, vm.fieldType(typ, name), env); if err != nil { return nil, err }
+		obj.Fields[name] = val
+	}
+	return obj, nil
+}
+
+func isBasicLit(e ast.Expr) bool { _, ok := e.(*ast.BasicLit); return ok }
+
+// evalElem evaluates one element of a composite literal for a location of
+// type typ. An untyped inner literal takes typ; for *T it means &T{...}.
+func (vm *Interpreter) evalElem(e ast.Expr, typ string, env *Env) (any, error) {
+	if lit, ok := e.(*ast.CompositeLit); ok && lit.Type == nil {
+		if !strings.HasPrefix(typ, "*") { return vm.evalComposite(lit, typ, env) }
+		v, err := vm.evalComposite(lit, typ[1:], env); if err != nil { return nil, err }
+		return &PointerVal{ElementType: typ[1:], Ref: &cellRef{v: v}}, nil
+	}
+	v, err := vm.evalExpr(e, env); if err != nil { return nil, err }
+	return vm.convertTo(typ, v)
+}
+
+// declareType registers a type declaration, generic or not. Aliases
+// (type A = B) only record the type they stand for.
+func (vm *Interpreter) declareType(ts *ast.TypeSpec) {
+	if ts.Assign.IsValid() { vm.aliases[ts.Name.Name] = typeString(ts.Type); return }
+	td := &TypeDef{Name: ts.Name.Name, Methods: map[string]*Function{}}
+	if ts.TypeParams != nil {
+		for _, f := range ts.TypeParams.List { for _, n := range f.Names { td.TypeParams = append(td.TypeParams, n.Name) } }
+	}
+	switch tt := ts.Type.(type) {
+	case *ast.StructType:
+		td.Kind = "struct"; td.Fields = []FieldDef{}
+		for _, f := range tt.Fields.List {
+			ft := typeString(f.Type)
+			if len(f.Names) == 0 { td.Fields = append(td.Fields, FieldDef{Name: embeddedName(ft), Type: ft, Embedded: true}); continue }
+			for _, n := range f.Names { td.Fields = append(td.Fields, FieldDef{Name: n.Name, Type: ft}) }
+		}
+	case *ast.InterfaceType:
+		td.Kind = "interface"; td.Signatures = map[string]string{}
+		for _, m := range tt.Methods.List {
+			if len(m.Names) == 0 { td.Embeds = append(td.Embeds, typeString(m.Type)); continue }
+			for _, n := range m.Names { td.MethodSet = append(td.MethodSet, n.Name); td.Signatures[n.Name] = typeString(m.Type) }
+		}
+	default:
+		// a defined type over any other type: type Celsius float64
+		td.Kind = "named"; td.Underlying = typeString(tt)
+	}
+	vm.types[td.Name] = td
+	vm.completeType(td)
+}
+
+// completeType gives a type defined over a struct or interface type, as in
+// type Admin User, the fields or method set of that type but not its methods.
+func (vm *Interpreter) completeType(td *TypeDef) {
+	if td.Kind != "named" { return }
+	u := vm.lookupType(vm.underlyingType(td.Underlying)); if u == nil || u.Kind == "named" { return }
+	td.Kind, td.Fields, td.MethodSet, td.Signatures, td.Embeds, td.Underlying = u.Kind, u.Fields, u.MethodSet, u.Signatures, u.Embeds, ""
+}
+
+// embeddedName is the field name of an embedded type: *pkg.List[T] -> List.
+func embeddedName(typ string) string {
+	base, _ := splitTypeArgs(strings.TrimPrefix(typ, "*"))
+	return base[strings.LastIndexByte(base, '.')+1:]
+}
+
+// setSignature records type parameters, parameter names and types and
+// result types of a function signature.
+func setSignature(fn *Function, ft *ast.FuncType) {
+	if ft.TypeParams != nil {
+		for _, f := range ft.TypeParams.List {
+			c := typeString(f.Type)
+			for _, n := range f.Names { fn.TypeParams = append(fn.TypeParams, n.Name); fn.Constraints = append(fn.Constraints, c) }
+		}
+	}
+	fn.ResultTypes = fieldTypes(ft.Results)
+	if ft.Results != nil {
+		for _, f := range ft.Results.List { for _, n := range f.Names { fn.ResultNames = append(fn.ResultNames, n.Name) } }
+	}
+	if ft.Params == nil { return }
+	for i, f := range ft.Params.List {
+		pt := typeString(f.Type)
+		for _, n := range f.Names { fn.Params = append(fn.Params, n.Name); fn.ParamTypes = append(fn.ParamTypes, pt) }
+		// variadic if last param is *ast.Ellipsis
+		if i == len(ft.Params.List)-1 {
+			if _, ok := f.Type.(*ast.Ellipsis); ok { fn.IsVariadic = true }
+		}
+	}
+}
+
+// declareValueSpec declares the names of a var/const spec in env. Names
+// with an explicit type keep it, so later assignments are checked.
+func (vm *Interpreter) declareValueSpec(vs *ast.ValueSpec, env *Env) error {
+	vals, err := vm.evalValueSpec(vs, env); if err != nil { return err }
+	for i, name := range vs.Names {
+		if name.Name == "_" { continue }
+		if vs.Type == nil { vm.declare(name.Name, vals[i], env); continue }
+		if err := vm.declareTyped(name.Name, vm.resolveType(typeString(vs.Type), env), vals[i], env); err != nil { return err }
+	}
+	return nil
+}
+
+// evalValueSpec yields one value per name of a var/const spec, unpacking
+// var a, b = f() and falling back to the zero value of the declared type.
+func (vm *Interpreter) evalValueSpec(vs *ast.ValueSpec, env *Env) ([]any, error) {
+	vals := make([]any, len(vs.Names))
+	if len(vs.Values) == 0 {
+		for i := range vals { vals[i] = vm.zeroValue(vm.resolveType(typeString(vs.Type), env)) }
+		return vals, nil
+	}
+	if len(vs.Values) == 1 && len(vs.Names) > 1 {
+		if len(vs.Names) == 2 {
+			pair, ok, err := vm.evalCommaOk(vs.Values[0], env); if err != nil { return nil, err }
+			if ok { copy(vals, pair); return vals, nil }
+		}
+		v, err := vm.evalExpr(vs.Values[0], env); if err != nil { return nil, err }
+		tv, ok := v.(*TupleVal)
+		if !ok || len(tv.Values) != len(vs.Names) { return nil, NewRuntimeError(fmt.Sprintf("assignment mismatch: %d variables but 1 value", len(vs.Names))) }
+		copy(vals, tv.Values)
+		return vals, nil
+	}
+	if len(vs.Values) != len(vs.Names) { return nil, NewRuntimeError(fmt.Sprintf("assignment mismatch: %d variables but %d values", len(vs.Names), len(vs.Values))) }
+	typ := ""; if vs.Type != nil { typ = vm.resolveType(typeString(vs.Type), env) }
+	for i, e := range vs.Values {
+		v, err := vm.evalAs(e, typ, env); if err != nil { return nil, err }
+		vals[i] = v
+	}
+	return vals, nil
+}
+
 // ---------------- Helpers ----------------------------------------
 
 func (vm *Interpreter) applyBinaryOp(op token.Token, left, right any) (any, error) {
+	if isNumber(left) && isNumber(right) { return numericOp(op, left, right) }
+	// Operators on defined types work on the underlying values; the result
+	// keeps the defined type, except for comparisons.
+	ln, lok := left.(*NamedVal); rn, rok := right.(*NamedVal)
+	if lok || rok {
+		switch op {
+		case token.EQL: return equals(left, right), nil
+		case token.NEQ: return !equals(left, right), nil
+		}
+		v, err := vm.applyBinaryOp(op, underlying(left), underlying(right)); if err != nil { return nil, err }
+		switch op {
+		case token.LSS, token.GTR, token.LEQ, token.GEQ: return v, nil
+		case token.SHL, token.SHR: rok = false
+		}
+		if lok { return &NamedVal{TypeName: ln.TypeName, Value: v}, nil }
+		if rok { return &NamedVal{TypeName: rn.TypeName, Value: v}, nil }
+		return v, nil
+	}
 	switch op {
 	case token.ADD:
 		if _, ok := left.(string); ok { return ToString(left)+ToString(right), nil }
@@ -786,38 +1544,180 @@ func (vm *Interpreter) applyBinaryOp(op token.Token, left, right any) (any, erro
 	case token.LOR:		return ToBool(left) || ToBool(right), nil
 	case token.EQL:		return equals(left, right), nil
 	case token.NEQ:		return !equals(left, right), nil
-	case token.LSS:		if _, ok := left.(float64); ok || isFloat(right) { return ToFloat(left) < ToFloat(right), nil }; return ToInt(left) < ToInt(right), nil
-	case token.GTR:		if _, ok := left.(float64); ok || isFloat(right) { return ToFloat(left) > ToFloat(right), nil }; return ToInt(left) > ToInt(right), nil
-	case token.LEQ:		if _, ok := left.(float64); ok || isFloat(right) { return ToFloat(left) <= ToFloat(right), nil }; return ToInt(left) <= ToInt(right), nil
-	case token.GEQ:		if _, ok := left.(float64); ok || isFloat(right) { return ToFloat(left) >= ToFloat(right), nil }; return ToInt(left) >= ToInt(right), nil
+	case token.LSS:		if s, ok := left.(string); ok { return s < ToString(right), nil }; if _, ok := left.(float64); ok || isFloat(right) { return ToFloat(left) < ToFloat(right), nil }; return ToInt(left) < ToInt(right), nil
+	case token.GTR:		if s, ok := left.(string); ok { return s > ToString(right), nil }; if _, ok := left.(float64); ok || isFloat(right) { return ToFloat(left) > ToFloat(right), nil }; return ToInt(left) > ToInt(right), nil
+	case token.LEQ:		if s, ok := left.(string); ok { return s <= ToString(right), nil }; if _, ok := left.(float64); ok || isFloat(right) { return ToFloat(left) <= ToFloat(right), nil }; return ToInt(left) <= ToInt(right), nil
+	case token.GEQ:		if s, ok := left.(string); ok { return s >= ToString(right), nil }; if _, ok := left.(float64); ok || isFloat(right) { return ToFloat(left) >= ToFloat(right), nil }; return ToInt(left) >= ToInt(right), nil
 	default:		return nil, NewRuntimeError("unsupported binary op")
 	}
 }
 
+func applyUnaryOp(op token.Token, v any) (any, error) {
+	switch op {
+	case token.NOT:		return !ToBool(v), nil
+	case token.SUB, token.ADD, token.XOR:
+		if isNumber(v) { return unaryOp(op, v) }
+		return unaryOp(op, ToInt(v))
+	default:			return nil, NewRuntimeError("unsupported unary op")
+	}
+}
+
 func isFloat(v any) bool { _, ok := v.(float64); return ok }
 
+// funcSignature renders the type of a function value, e.g. "func(int) string".
+// Natives without declared parameter types are just "func".
+func funcSignature(fn *Function) string {
+	if fn.Native != nil && fn.ParamTypes == nil { return "func" }
+	s := "func(" + strings.Join(fn.ParamTypes, ", ") + ")"
+	switch len(fn.ResultTypes) {
+	case 0:
+	case 1: s += " " + fn.ResultTypes[0]
+	default: s += " (" + strings.Join(fn.ResultTypes, ", ") + ")"
+	}
+	return s
+}
+
 func typeOfValue(vm *Interpreter, v any) string {
 	switch x := v.(type) {
 	case *StructVal: return x.TypeName
 	case *SliceVal:  return "[]"+x.ElementType
-	case *MapVal:    return "map"
+	case *MapVal:    return "map["+x.KeyType+"]"+x.ElementType
 	case *ChannelVal:return "chan "+x.ElementType
+	case *PointerVal:return "*"+x.ElementType
+	case *NamedVal:  return x.TypeName
+	case *ArrayVal:  return fmt.Sprintf("[%d]%s", len(x.Data), x.ElementType)
 	case int:        return "int"
 	case float64:    return "float64"
 	case bool:       return "bool"
 	case string:     return "string"
-	case *Function:  return "func"
+	case *Function:  return funcSignature(x)
+	case nil:        return "nil"
 	default:         return fmt.Sprintf("%T", v)
 	}
 }
 
+// typeMatches reports whether v's dynamic type satisfies the type named
+// by typ, as checked by type assertions and type switch cases.
+func (vm *Interpreter) typeMatches(v any, typ string) bool {
+	switch {
+	case typ == "any":
+		return v != nil
+	case typ == "error":
+		_, _, err := vm.methodOf(v, "Error"); return v != nil && err == nil
+	case strings.HasPrefix(typ, "func("):
+		_, ok := v.(*Function); return ok
+	case strings.HasPrefix(typ, "interface{"):
+		return v != nil && vm.satisfies(typeOfValue(vm, v), typ)
+	}
+	if td := vm.lookupType(typ); td != nil && td.Kind == "interface" { return v != nil && vm.missingMethod(v, typ) == "" }
+	return typeOfValue(vm, v) == canonicalType(vm.unalias(typ))
+}
+
+// findMethod looks up a method on the dynamic type of recv. Host error
+// values expose their Error method so they satisfy the error interface.
+func (vm *Interpreter) findMethod(recv any, name string) (*Function, error) {
+	recvType := typeOfValue(vm, recv)
+	if td := vm.lookupType(strings.TrimPrefix(recvType, "*")); td != nil && td.Methods != nil {
+		if fn := td.Methods[name]; fn != nil { return fn, nil }
+		return nil, NewRuntimeError("method not found: " + recvType + "." + name)
+	}
+	if e, ok := recv.(error); ok && name == "Error" {
+		return &Function{Name: "Error", Native: func(args []any) (any, error) { return e.Error(), nil }}, nil
+	}
+	return nil, NewRuntimeError("unknown method on type " + recvType)
+}
+
+// missingMethod explains why v does not implement the interface type
+// iface, or returns "". Methods with pointer receivers are only in the
+// method set of pointers, and each method must have the declared signature.
+func (vm *Interpreter) missingMethod(v any, iface string) string {
+	td := vm.lookupType(iface); if td == nil { return "" }
+	bindings := instanceBindings(td, iface)
+	_, isPtr := v.(*PointerVal)
+	for _, m := range td.MethodSet {
+		fn, holder, err := vm.methodOf(v, m)
+		if err != nil { return "missing method " + m }
+		// A promoted method is reachable when an embedded pointer leads to it.
+		if fn.RecvPtr && !isPtr && (holder == nil || !vm.typeHasMethod(typeOfValue(vm, v), m)) {
+			return "method " + m + " has pointer receiver"
+		}
+		if t := vm.methodType(typeOfValue(vm, v), m); t != "" && !vm.sameSignature(t, substTypes(td.Signatures[m], bindings)) {
+			return "wrong type for method " + m
+		}
+	}
+	// Embedded interfaces contribute their methods.
+	for _, el := range td.Embeds {
+		el = substTypes(el, bindings)
+		if el == "error" && !vm.typeMatches(v, "error") { return "missing method Error" }
+		if et := vm.lookupType(el); et != nil && et.Kind == "interface" {
+			if why := vm.missingMethod(v, el); why != "" { return why }
+		}
+	}
+	return ""
+}
+
+// convertTo prepares v for storage in a location of static type typ: values
+// stored into an interface type must implement it, and structs are copied.
+func (vm *Interpreter) convertTo(typ string, v any) (any, error) {
+	if v == nil { return v, nil }
+	missing := ""
+	if td := vm.lookupType(typ); td != nil && td.Kind == "interface" {
+		missing = vm.missingMethod(v, typ)
+	} else if typ == "error" && !vm.typeMatches(v, "error") {
+		missing = "missing method Error"
+	}
+	if missing != "" {
+		t := typeOfValue(vm, v)
+		return nil, NewRuntimeError(fmt.Sprintf("cannot use %s value as %s value: %s does not implement %s (%s)", t, typ, t, typ, missing))
+	}
+	return vm.valueAs(typ, copyValue(v)), nil
+}
+
+// valueAs gives a value the type typ of the location it is stored in:
+// numbers get the numeric type, other values a defined type over theirs.
+func (vm *Interpreter) valueAs(typ string, v any) any {
+	if td := vm.lookupType(typ); td != nil && td.Kind == "named" && v != nil {
+		if _, ok := v.(*NamedVal); ok { return v }
+		return &NamedVal{TypeName: vm.unalias(typ), Value: numberAs(vm.underlyingType(typ), v)}
+	}
+	return numberAs(vm.unalias(typ), v)
+}
+
+// fieldType returns the declared type of a struct field, or "" if unknown.
+// Fields of an instantiated generic type get the type arguments substituted.
+func (vm *Interpreter) fieldType(typeName, field string) string {
+	if td := vm.lookupType(typeName); td != nil {
+		for _, f := range td.Fields { if f.Name == field { return substTypes(f.Type, instanceBindings(td, typeName)) } }
+	}
+	return ""
+}
+
 func equals(a, b any) bool {
+	// Values of different defined types are never equal: any(W(1)) != any(1)
+	an, aok := a.(*NamedVal); bn, bok := b.(*NamedVal)
+	if (aok || bok) && a != nil && b != nil && (!aok || !bok || an.TypeName != bn.TypeName) { return false }
+	a, b = underlying(a), underlying(b)
+	if a == nil || b == nil { return isNil(a) && isNil(b) }
+	if isNumber(a) && isNumber(b) { r, err := numericOp(token.EQL, a, b); return err == nil && r == true }
 	switch x := a.(type) {
 	case int:     return x == ToInt(b)
 	case float64: return x == ToFloat(b)
 	case bool:    return x == ToBool(b)
 	case string:  return x == ToString(b)
-	case *StructVal: return hashKey(a) == hashKey(b)
+	case *StructVal, *ArrayVal: return hashKey(a) == hashKey(b)
+	case *PointerVal: q, ok := b.(*PointerVal); return ok && refAddr(x.Ref) == refAddr(q.Ref)
 	default:      return a == b
 	}
 }
+
+// isNil reports whether v is nil, including typed nil values such as a
+// channel declared with var but never made.
+func isNil(v any) bool {
+	switch x := v.(type) {
+	case nil:         return true
+	case *ChannelVal: return x == nil || x.C == nil
+	case *PointerVal: return x == nil || x.Ref == nil
+	case *NamedVal:   return isNil(x.Value)
+	}
+	return false
+}
@@ -0,0 +1,328 @@
+// interp/generics.go
+package interp
+
+import (
+	"fmt"
+	"strings"
+)
+
+// typeBinding is what a type parameter name is bound to inside the body of
+// a generic function or method, e.g. T -> "int" for Map[int, string].
+type typeBinding struct{ typ string }
+
+// lookupType finds the TypeDef for a type string, ignoring instantiation
+// arguments (Stack[int] -> Stack).
+func (vm *Interpreter) lookupType(typ string) *TypeDef {
+	if td := vm.types[typ]; td != nil { return td }
+	if a, ok := vm.aliases[typ]; ok { return vm.lookupType(a) }
+	if strings.HasPrefix(typ, "struct{") { return anonStruct(typ) }
+	if base, args := splitTypeArgs(typ); len(args) > 0 { return vm.types[base] }
+	return nil
+}
+
+// unalias resolves a type alias declared with type A = B.
+func (vm *Interpreter) unalias(typ string) string {
+	for a, ok := vm.aliases[typ]; ok; a, ok = vm.aliases[typ] { typ = a }
+	return typ
+}
+
+// underlyingType follows defined types down to the type they are defined
+// over: Celsius -> float64, List[int] -> []int. Other types are their own.
+func (vm *Interpreter) underlyingType(typ string) string {
+	for seen := 0; seen <= len(vm.types); seen++ {
+		td := vm.lookupType(typ); if td == nil || td.Kind != "named" { return vm.unalias(typ) }
+		typ = substTypes(td.Underlying, instanceBindings(td, typ))
+	}
+	return typ
+}
+
+// anonStruct describes a struct type literal such as "struct{X int; Y int}".
+func anonStruct(typ string) *TypeDef {
+	td := &TypeDef{Name: typ, Kind: "struct", Fields: []FieldDef{}}
+	for _, f := range splitTopLevel(typ[len("struct{"):len(typ)-1], ';') {
+		if name, ft, ok := strings.Cut(f, " "); ok {
+			td.Fields = append(td.Fields, FieldDef{Name: name, Type: ft})
+		} else {
+			td.Fields = append(td.Fields, FieldDef{Name: embeddedName(f), Type: f, Embedded: true})
+		}
+	}
+	return td
+}
+
+// instanceBindings maps the type parameters of a generic TypeDef to the
+// arguments of an instantiated type string such as "Pair[string, int]".
+func instanceBindings(td *TypeDef, typ string) map[string]string {
+	if td == nil || len(td.TypeParams) == 0 { return nil }
+	_, args := splitTypeArgs(typ)
+	out := map[string]string{}
+	for i, p := range td.TypeParams { if i < len(args) { out[p] = args[i] } }
+	return out
+}
+
+// resolveType substitutes type parameters bound in env into typ.
+func (vm *Interpreter) resolveType(typ string, env *Env) string {
+	return replaceTypeIdents(typ, func(name string) (string, bool) {
+		if isBuiltinType(name) || vm.types[name] != nil { return "", false }
+		if a, ok := vm.aliases[name]; ok { return vm.resolveType(a, env), true }
+		if v, ok := vm.get(name, env); ok {
+			if tb, ok := v.(*typeBinding); ok { return tb.typ, true }
+		}
+		return "", false
+	})
+}
+
+// substTypes substitutes type parameters from an explicit binding map.
+func substTypes(typ string, bindings map[string]string) string {
+	if len(bindings) == 0 { return typ }
+	return replaceTypeIdents(typ, func(name string) (string, bool) { t, ok := bindings[name]; return t, ok })
+}
+
+// replaceTypeIdents rewrites every identifier of a type string for which f
+// reports a replacement.
+func replaceTypeIdents(typ string, f func(string) (string, bool)) string {
+	var b strings.Builder
+	for i := 0; i < len(typ); {
+		c := typ[i]
+		if !isIdentByte(c) || (c >= '0' && c <= '9') { b.WriteByte(c); i++; continue }
+		j := i
+		for j < len(typ) && isIdentByte(typ[j]) { j++ }
+		if r, ok := f(typ[i:j]); ok { b.WriteString(r) } else { b.WriteString(typ[i:j]) }
+		i = j
+	}
+	return b.String()
+}
+
+func isIdentByte(c byte) bool { return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= 0x80 }
+
+// splitTypeArgs splits "Pair[string, int]" into "Pair" and its arguments.
+// Slice, array and map types are not instantiations and yield no arguments.
+func splitTypeArgs(typ string) (string, []string) {
+	i := strings.IndexByte(typ, '[')
+	if i <= 0 || !strings.HasSuffix(typ, "]") || strings.HasPrefix(typ, "map[") { return typ, nil }
+	for _, c := range []byte(typ[:i]) { if !isIdentByte(c) { return typ, nil } }
+	return typ[:i], splitTopLevel(typ[i+1:len(typ)-1], ',')
+}
+
+// splitTopLevel splits s at sep, ignoring separators nested in brackets.
+func splitTopLevel(s string, sep byte) []string {
+	var out []string
+	depth := 0; start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '[', '(', '{': depth++
+		case ']', ')', '}': depth--
+		case sep:
+			if depth == 0 { out = append(out, strings.TrimSpace(s[start:i])); start = i+1 }
+		}
+	}
+	if rest := strings.TrimSpace(s[start:]); rest != "" || len(out) > 0 { out = append(out, rest) }
+	return out
+}
+
+// splitFuncType splits "func(A, B) (R1, R2)" into parameter and result types.
+func splitFuncType(typ string) (params, results []string, ok bool) {
+	if !strings.HasPrefix(typ, "func(") { return nil, nil, false }
+	depth := 0
+	for i := 4; i < len(typ); i++ {
+		switch typ[i] {
+		case '(', '[', '{': depth++
+		case ')', ']', '}':
+			depth--
+			if depth == 0 {
+				params = splitTopLevel(typ[5:i], ',')
+				rest := strings.TrimSpace(typ[i+1:])
+				if strings.HasPrefix(rest, "(") && strings.HasSuffix(rest, ")") { results = splitTopLevel(rest[1:len(rest)-1], ',') } else if rest != "" { results = []string{rest} }
+				return params, results, true
+			}
+		}
+	}
+	return nil, nil, false
+}
+
+// unifyTypes matches a parameter type pattern against a concrete type and
+// records bindings for the type parameters it contains.
+func unifyTypes(pat, conc string, params map[string]bool, out map[string]string) {
+	if params[pat] {
+		if _, ok := out[pat]; !ok && conc != "" && conc != "nil" { out[pat] = conc }
+		return
+	}
+	switch {
+	case strings.HasPrefix(pat, "[]") && strings.HasPrefix(conc, "[]"):
+		unifyTypes(pat[2:], conc[2:], params, out)
+	case strings.HasPrefix(pat, "*") && strings.HasPrefix(conc, "*"):
+		unifyTypes(pat[1:], conc[1:], params, out)
+	case strings.HasPrefix(pat, "chan ") && strings.HasPrefix(conc, "chan "):
+		unifyTypes(pat[5:], conc[5:], params, out)
+	case strings.HasPrefix(pat, "map[") && strings.HasPrefix(conc, "map["):
+		pk, pv := parseMapType(pat); ck, cv := parseMapType(conc)
+		unifyTypes(pk, ck, params, out); unifyTypes(pv, cv, params, out)
+	case strings.HasPrefix(pat, "func(") && strings.HasPrefix(conc, "func("):
+		pp, pr, _ := splitFuncType(pat); cp, cr, _ := splitFuncType(conc)
+		for i := 0; i < len(pp) && i < len(cp); i++ { unifyTypes(pp[i], cp[i], params, out) }
+		for i := 0; i < len(pr) && i < len(cr); i++ { unifyTypes(pr[i], cr[i], params, out) }
+	default:
+		pb, pa := splitTypeArgs(pat); cb, ca := splitTypeArgs(conc)
+		if len(pa) > 0 && pb == cb {
+			for i := 0; i < len(pa) && i < len(ca); i++ { unifyTypes(pa[i], ca[i], params, out) }
+		}
+	}
+}
+
+// typeArgsFor works out the type arguments of a call to a generic function:
+// from the receiver's instantiation for methods, otherwise from explicit
+// instantiation followed by inference over the argument types.
+func (vm *Interpreter) typeArgsFor(fn *Function, recv *any, args []any) ([]string, error) {
+	if fn.RecvType != "" {
+		if recv == nil { return nil, NewRuntimeError("generic method call without receiver") }
+		_, targs := splitTypeArgs(strings.TrimPrefix(typeOfValue(vm, *recv), "*"))
+		if len(targs) != len(fn.TypeParams) { return nil, NewRuntimeError("cannot instantiate method " + fn.Name) }
+		return targs, nil
+	}
+	params := map[string]bool{}
+	out := map[string]string{}
+	for i, p := range fn.TypeParams {
+		params[p] = true
+		if i < len(fn.TypeArgs) { out[p] = fn.TypeArgs[i] }
+	}
+	for i, pt := range fn.ParamTypes {
+		if strings.HasPrefix(pt, "...") {
+			for _, a := range args[min(i, len(args)):] {
+				if sa, ok := a.(*spreadArg); ok { unifyTypes("[]"+pt[3:], typeOfValue(vm, sa.s), params, out) } else { unifyTypes(pt[3:], typeOfValue(vm, a), params, out) }
+			}
+			break
+		}
+		if i < len(args) { unifyTypes(pt, typeOfValue(vm, args[i]), params, out) }
+	}
+	// Core types: [S ~[]E, E any] infers E from the binding of S.
+	for changed := true; changed; {
+		changed = false
+		for i, p := range fn.TypeParams {
+			bound, ok := out[p]; if !ok { continue }
+			before := len(out)
+			unifyTypes(strings.TrimPrefix(fn.Constraints[i], "~"), bound, params, out)
+			if len(out) != before { changed = true }
+		}
+	}
+	targs := make([]string, len(fn.TypeParams))
+	for i, p := range fn.TypeParams {
+		t, ok := out[p]; if !ok { return nil, NewRuntimeError(fmt.Sprintf("in call to %s, cannot infer %s", fn.Name, p)) }
+		if c := substTypes(fn.Constraints[i], out); !vm.satisfies(t, c) {
+			return nil, NewRuntimeError(fmt.Sprintf("%s does not satisfy %s", t, c))
+		}
+		targs[i] = t
+	}
+	return targs, nil
+}
+
+// instantiate returns a copy of a generic function with explicit type
+// arguments, as produced by Map[int, string].
+func (vm *Interpreter) instantiate(fn *Function, targs []string) (*Function, error) {
+	if len(fn.TypeParams) == 0 { return nil, NewRuntimeError(fmt.Sprintf("%s is not a generic function", fn.Name)) }
+	if len(targs) > len(fn.TypeParams) { return nil, NewRuntimeError(fmt.Sprintf("got %d type arguments but %s has %d type parameters", len(targs), fn.Name, len(fn.TypeParams))) }
+	inst := *fn
+	inst.TypeArgs = targs
+	return &inst, nil
+}
+
+// satisfies reports whether the type typ is in the type set of constraint.
+func (vm *Interpreter) satisfies(typ, constraint string) bool {
+	constraint = strings.TrimSpace(constraint)
+	switch constraint {
+	case "", "any": return true
+	case "comparable": return isComparableType(typ)
+	}
+	if terms := splitTopLevel(constraint, '|'); len(terms) > 1 {
+		for _, t := range terms { if vm.satisfies(typ, t) { return true } }
+		return false
+	}
+	if strings.HasPrefix(constraint, "~") { return vm.underlyingType(typ) == strings.TrimSpace(constraint[1:]) }
+	if strings.HasPrefix(constraint, "interface{") {
+		for _, el := range splitTopLevel(constraint[len("interface{"):len(constraint)-1], ';') {
+			if name, sig, ok := methodElem(el); ok {
+				if !vm.typeHasMethod(typ, name) || !vm.sameSignature(vm.methodType(typ, name), sig) { return false }
+			} else if !vm.satisfies(typ, el) { return false }
+		}
+		return true
+	}
+	if td := vm.lookupType(constraint); td != nil && td.Kind == "interface" {
+		bindings := instanceBindings(td, constraint)
+		for _, m := range td.MethodSet {
+			if !vm.typeHasMethod(typ, m) || !vm.sameSignature(vm.methodType(typ, m), substTypes(td.Signatures[m], bindings)) { return false }
+		}
+		for _, el := range td.Embeds { if !vm.satisfies(typ, substTypes(el, bindings)) { return false } }
+		return true
+	}
+	return typ == constraint
+}
+
+// typeHasMethod reports whether name is in the method set of typ; pointer
+// receiver methods only count for pointer types.
+func (vm *Interpreter) typeHasMethod(typ, name string) bool {
+	ptr := strings.HasPrefix(typ, "*")
+	td := vm.lookupType(strings.TrimPrefix(typ, "*"))
+	if td == nil { return false }
+	if m := td.Methods[name]; m != nil { return !m.RecvPtr || ptr }
+	// Promoted methods: through *Outer, an embedded T is addressable.
+	for _, f := range td.Fields {
+		if !f.Embedded { continue }
+		et := f.Type; if ptr && !strings.HasPrefix(et, "*") { et = "*" + et }
+		if vm.typeHasMethod(et, name) { return true }
+	}
+	return false
+}
+
+// methodType is the func type of the method name of typ, with the type
+// parameters of a generic receiver bound, or "" if typ has no such method.
+func (vm *Interpreter) methodType(typ, name string) string {
+	base := strings.TrimPrefix(typ, "*")
+	td := vm.lookupType(base); if td == nil { return "" }
+	bindings := instanceBindings(td, base)
+	if m := td.Methods[name]; m != nil {
+		// The receiver may name the type parameters differently: func (s *Stack[E]) Push(v E)
+		_, args := splitTypeArgs(base)
+		mb := map[string]string{}
+		for i, p := range m.TypeParams { if i < len(args) { mb[p] = args[i] } }
+		return substTypes(funcType(m), mb)
+	}
+	for _, f := range td.Fields {
+		if !f.Embedded { continue }
+		if t := vm.methodType(substTypes(f.Type, bindings), name); t != "" { return t }
+	}
+	return ""
+}
+
+// funcType spells the signature of fn the way typeString spells a func type.
+func funcType(fn *Function) string {
+	s := "func(" + strings.Join(fn.ParamTypes, ", ") + ")"
+	switch len(fn.ResultTypes) {
+	case 0:
+	case 1: s += " " + fn.ResultTypes[0]
+	default: s += " (" + strings.Join(fn.ResultTypes, ", ") + ")"
+	}
+	return s
+}
+
+// sameSignature reports whether two func types have identical parameter
+// and result types.
+func (vm *Interpreter) sameSignature(a, b string) bool {
+	ap, ar, ok1 := splitFuncType(a); bp, br, ok2 := splitFuncType(b)
+	if !ok1 || !ok2 { return a == b }
+	same := func(x, y []string) bool {
+		if len(x) != len(y) { return false }
+		for i := range x { if canonicalType(vm.unalias(strings.TrimSpace(x[i]))) != canonicalType(vm.unalias(strings.TrimSpace(y[i]))) { return false } }
+		return true
+	}
+	return same(ap, bp) && same(ar, br)
+}
+
+// methodElem splits a method element of an interface literal such as
+// "Area() float64" into its name and func type.
+func methodElem(el string) (name, sig string, ok bool) {
+	i := strings.IndexByte(el, '('); if i <= 0 || el[:i] == "func" { return "", "", false }
+	for j := 0; j < i; j++ { if !isIdentByte(el[j]) { return "", "", false } }
+	return el[:i], "func" + el[i:], true
+}
+
+func isComparableType(typ string) bool {
+	return !strings.HasPrefix(typ, "[]") && !strings.HasPrefix(typ, "map[") && !strings.HasPrefix(typ, "func(")
+}
@@ -0,0 +1,118 @@
+// interp/initorder.go
+package interp
+
+import (
+	"go/ast"
+	"go/token"
+	"sort"
+	"strings"
+)
+
+// initVars initialises package-level variables in Go's order: again and
+// again the earliest spec in declaration order whose initialiser depends on
+// no uninitialised variable, directly or through the functions and methods
+// it refers to. Should only dependent specs remain, they form a cycle.
+func (vm *Interpreter) initVars(specs []*ast.ValueSpec, env *Env) error {
+	pending := map[string]bool{}
+	specOf := map[string]int{}
+	deps := make([]map[string]bool, len(specs))
+	for i, vs := range specs {
+		for _, n := range vs.Names { pending[n.Name] = true; specOf[n.Name] = i }
+		deps[i] = map[string]bool{}
+		seen := map[*Function]bool{}
+		for _, e := range vs.Values { vm.varRefs(e, map[string]bool{}, deps[i], seen) }
+	}
+	done := make([]bool, len(specs))
+	for range specs {
+		next, first := -1, -1
+		for i := range specs {
+			if done[i] { continue }
+			if first < 0 { first = i }
+			ready := true
+			for n := range deps[i] { if pending[n] { ready = false; break } }
+			if ready { next = i; break }
+		}
+		if next < 0 { return initCycle(specs, deps, specOf, pending, first) }
+		done[next] = true
+		if err := vm.declareValueSpec(specs[next], env); err != nil { return err }
+		for _, n := range specs[next].Names { delete(pending, n.Name) }
+	}
+	return nil
+}
+
+// initCycle reports the cycle reached by following, from spec start, the
+// first uninitialised variable each spec depends on.
+func initCycle(specs []*ast.ValueSpec, deps []map[string]bool, specOf map[string]int, pending map[string]bool, start int) error {
+	names := []string{specs[start].Names[0].Name}
+	seen := map[int]int{start: 0}
+	for i := start; ; {
+		var refs []string
+		for n := range deps[i] { if pending[n] { refs = append(refs, n) } }
+		sort.Slice(refs, func(a, b int) bool { return specOf[refs[a]] < specOf[refs[b]] || specOf[refs[a]] == specOf[refs[b]] && refs[a] < refs[b] })
+		names = append(names, refs[0])
+		i = specOf[refs[0]]
+		if at, ok := seen[i]; ok { names = names[at:]; break }
+		seen[i] = len(names) - 1
+	}
+	steps := make([]string, len(names)-1)
+	for i := range steps { steps[i] = names[i] + " refers to " + names[i+1] }
+	if names[0] == names[1] { steps[0] = names[0] + " refers to itself" }
+	return NewRuntimeError("initialization cycle: " + strings.Join(steps, ", "))
+}
+
+// varRefs adds the package-level names that root refers to to out, and
+// follows the bodies of the functions it mentions. A selector x.M may name
+// the method M of any type. Names in local are declared inside root.
+func (vm *Interpreter) varRefs(root ast.Node, local, out map[string]bool, seen map[*Function]bool) {
+	localNames(root, local)
+	var visit func(ast.Node) bool
+	visit = func(n ast.Node) bool {
+		switch x := n.(type) {
+		case *ast.Ident:
+			if local[x.Name] { return true }
+			out[x.Name] = true
+			if fn := vm.funcs[x.Name]; fn != nil { vm.funcRefs(fn, out, seen) }
+		case *ast.SelectorExpr:
+			for _, td := range vm.types { if m := td.Methods[x.Sel.Name]; m != nil { vm.funcRefs(m, out, seen) } }
+			ast.Inspect(x.X, visit)
+			return false
+		}
+		return true
+	}
+	ast.Inspect(root, visit)
+}
+
+func (vm *Interpreter) funcRefs(fn *Function, out map[string]bool, seen map[*Function]bool) {
+	body, ok := fn.Body.(*ast.BlockStmt); if !ok || seen[fn] { return }
+	seen[fn] = true
+	local := map[string]bool{fn.RecvName: true}
+	for _, p := range fn.Params { local[p] = true }
+	for _, p := range fn.ResultNames { local[p] = true }
+	for _, p := range fn.TypeParams { local[p] = true }
+	vm.varRefs(body, local, out, seen)
+}
+
+// localNames adds the names declared anywhere inside n to local. Scopes are
+// not told apart, so a name shadowed in one block counts as local throughout.
+func localNames(n ast.Node, local map[string]bool) {
+	ident := func(e ast.Expr) { if id, ok := e.(*ast.Ident); ok { local[id.Name] = true } }
+	fields := func(fl *ast.FieldList) {
+		if fl == nil { return }
+		for _, f := range fl.List { for _, id := range f.Names { local[id.Name] = true } }
+	}
+	ast.Inspect(n, func(n ast.Node) bool {
+		switch x := n.(type) {
+		case *ast.AssignStmt:
+			if x.Tok == token.DEFINE { for _, e := range x.Lhs { ident(e) } }
+		case *ast.RangeStmt:
+			if x.Tok == token.DEFINE { ident(x.Key); ident(x.Value) }
+		case *ast.ValueSpec:
+			for _, id := range x.Names { local[id.Name] = true }
+		case *ast.TypeSpec:
+			local[x.Name.Name] = true
+		case *ast.FuncType:
+			fields(x.Params); fields(x.Results)
+		}
+		return true
+	})
+}
@@ -698,3 +698,1736 @@ func TestHashKey(t *testing.T) {
 		t.Error("int and string should hash differently")
 	}
 }
+
+func TestMultipleReturnValues(t *testing.T) {
+	out := runAndCapture(t, `
+package main
+import "fmt"
+func divmod(a, b int) (int, int) { return a / b, a % b }
+func pair() (string, int) { return "x", 7 }
+func show(s string, n int) { fmt.Println(s, n) }
+func swap(a, b int) (int, int) { return b, a }
+func forward() (int, int) { return swap(1, 2) }
+func main() {
+	q, r := divmod(7, 2)
+	fmt.Println(r)
+	_ = q
+	show(pair())
+	var a, b = forward()
+	fmt.Println(a, b)
+	a, b = b, a
+	fmt.Println(a, b)
+}
+`)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	expected := []string{"1", "x 7", "2 1", "1 2"}
+	for i, want := range expected {
+		if i >= len(lines) || strings.TrimSpace(lines[i]) != want {
+			t.Errorf("line %d: want %q, got %q", i, want, safeIndex(lines, i))
+		}
+	}
+}
+
+func TestNativeMultipleReturnValues(t *testing.T) {
+	out := runAndCapture(t, `
+package main
+import "fmt"
+import "strconv"
+func main() {
+	n, err := strconv.Atoi("42")
+	fmt.Println(n + 1, err == nil)
+	_, err = strconv.Atoi("nope")
+	if err != nil { fmt.Println("error:", err) }
+}
+`)
+	if !strings.Contains(out, "43 true") {
+		t.Errorf("expected '43 true', got %q", out)
+	}
+	if !strings.Contains(out, `error: strconv.Atoi: parsing "nope": invalid syntax`) {
+		t.Errorf("expected Atoi error, got %q", out)
+	}
+}
+
+func TestAssignmentCountMismatch(t *testing.T) {
+	vm, _ := newTestVM()
+	err := vm.Run(`
+package main
+func two() (int, int) { return 1, 2 }
+func main() { a, b, c := two(); _ = a; _ = b; _ = c }
+`)
+	if err == nil || !strings.Contains(err.Error(), "assignment mismatch") {
+		t.Fatalf("expected assignment mismatch error, got %v", err)
+	}
+}
+
+func TestSelectDefaultAndReady(t *testing.T) {
+	out := runAndCapture(t, `
+package main
+import "fmt"
+func main() {
+	ch := make(chan int, 1)
+	select {
+	case v := <-ch:
+		fmt.Println("got", v)
+	default:
+		fmt.Println("empty")
+	}
+	ch <- 5
+	select {
+	case v, ok := <-ch:
+		fmt.Println("got", v, ok)
+	default:
+		fmt.Println("empty")
+	}
+	out := make(chan string, 1)
+	select {
+	case out <- "sent":
+	}
+	fmt.Println(<-out)
+}
+`)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	expected := []string{"empty", "got 5 true", "sent"}
+	for i, want := range expected {
+		if i >= len(lines) || strings.TrimSpace(lines[i]) != want {
+			t.Errorf("line %d: want %q, got %q", i, want, safeIndex(lines, i))
+		}
+	}
+}
+
+func TestSelectFanInTimeoutAndNilChannel(t *testing.T) {
+	out := runAndCapture(t, `
+package main
+import "fmt"
+import "time"
+func main() {
+	a := make(chan int)
+	b := make(chan int)
+	go func() { for i := 0; i < 3; i++ { a <- 1 } }()
+	go func() { for i := 0; i < 3; i++ { b <- 10 } }()
+	sum := 0
+	for i := 0; i < 6; i++ {
+		select {
+		case v := <-a:
+			sum += v
+		case v := <-b:
+			sum += v
+		}
+	}
+	fmt.Println("sum", sum)
+
+	var never chan int
+	fmt.Println(never == nil)
+	select {
+	case <-never:
+		fmt.Println("nil channel ready")
+	case <-time.After(20):
+		fmt.Println("timeout")
+	}
+}
+`)
+	if !strings.Contains(out, "sum 33") {
+		t.Errorf("expected 'sum 33', got %q", out)
+	}
+	if !strings.Contains(out, "true\ntimeout") {
+		t.Errorf("expected nil channel and timeout, got %q", out)
+	}
+}
+
+func TestTypeAssertions(t *testing.T) {
+	out := runAndCapture(t, `
+package main
+import "fmt"
+func main() {
+	var v any = "hello"
+	s := v.(string)
+	fmt.Println(s)
+	n, ok := v.(int)
+	fmt.Println(n, ok)
+	var xs interface{} = []int{1, 2}
+	if ys, ok := xs.([]int); ok { fmt.Println(len(ys)) }
+	m := map[string]int{"a": 1}
+	var mv any = m
+	_, ok = mv.(map[string]int)
+	fmt.Println(ok)
+}
+`)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	expected := []string{"hello", "0 false", "2", "true"}
+	for i, want := range expected {
+		if i >= len(lines) || strings.TrimSpace(lines[i]) != want {
+			t.Errorf("line %d: want %q, got %q", i, want, safeIndex(lines, i))
+		}
+	}
+}
+
+func TestFailedTypeAssertionPanics(t *testing.T) {
+	vm, _ := newTestVM()
+	err := vm.Run(`
+package main
+func main() {
+	var v any = 3
+	_ = v.(string)
+}
+`)
+	if err == nil || !strings.Contains(err.Error(), "interface conversion: interface {} is int, not string") {
+		t.Fatalf("expected interface conversion panic, got %v", err)
+	}
+}
+
+func TestTypeSwitchOnJSON(t *testing.T) {
+	out := runAndCapture(t, `
+package main
+import "fmt"
+import "encoding/json"
+type Point struct { X int }
+func describe(v any) string {
+	switch x := v.(type) {
+	case nil:
+		return "nil"
+	case int, bool:
+		return fmt.Sprintf("int-or-bool %v", x)
+	case float64:
+		return fmt.Sprintf("number %v", x)
+	case string:
+		return "string " + x
+	case []any:
+		return fmt.Sprintf("list of %d", len(x))
+	case Point:
+		return fmt.Sprintf("point %d", x.X)
+	default:
+		return "other"
+	}
+}
+func main() {
+	src, _ := json.Marshal(map[string]any{"name": "nano", "n": 2, "tags": []int{1, 2, 3}, "none": nil})
+	doc, _ := json.Unmarshal(src)
+	obj := doc.(map[string]any)
+	fmt.Println(describe(obj["name"]))
+	fmt.Println(describe(obj["n"]))
+	fmt.Println(describe(obj["tags"]))
+	fmt.Println(describe(obj["none"]))
+	fmt.Println(describe(true))
+	fmt.Println(describe(Point{X: 4}))
+	fmt.Println(describe(obj))
+}
+`)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	expected := []string{"string nano", "number 2", "list of 3", "nil", "int-or-bool true", "point 4", "other"}
+	for i, want := range expected {
+		if i >= len(lines) || strings.TrimSpace(lines[i]) != want {
+			t.Errorf("line %d: want %q, got %q", i, want, safeIndex(lines, i))
+		}
+	}
+}
+
+func TestInterfacePolymorphism(t *testing.T) {
+	out := runAndCapture(t, `
+package main
+import "fmt"
+type Shape interface {
+	Area() float64
+	Name() string
+}
+type Rect struct { W, H float64 }
+func (r Rect) Area() float64 { return r.W * r.H }
+func (r Rect) Name() string { return "rect" }
+type Circle struct { R float64 }
+func (c Circle) Area() float64 { return 3.0 * c.R * c.R }
+func (c Circle) Name() string { return "circle" }
+type Scene struct { Main Shape }
+func describe(s Shape) string { return fmt.Sprintf("%s %v", s.Name(), s.Area()) }
+func main() {
+	shapes := []Shape{Rect{W: 2, H: 3}, Circle{R: 1}}
+	for _, s := range shapes { fmt.Println(describe(s)) }
+	var s Shape
+	fmt.Println(s == nil)
+	s = Circle{R: 2}
+	sc := Scene{Main: s}
+	fmt.Println(sc.Main.Name(), sc.Main.Area())
+	_, isRect := s.(Rect)
+	_, isShape := s.(Shape)
+	fmt.Println(isRect, isShape)
+}
+`)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	expected := []string{"rect 6", "circle 3", "true", "circle 12", "false true"}
+	for i, want := range expected {
+		if i >= len(lines) || strings.TrimSpace(lines[i]) != want {
+			t.Errorf("line %d: want %q, got %q", i, want, safeIndex(lines, i))
+		}
+	}
+}
+
+func TestInterfaceNotImplemented(t *testing.T) {
+	for _, body := range []string{
+		"var s Shape = Square{}; _ = s",
+		"var s Shape; s = Square{}",
+		"show(Square{})",
+		"_ = Holder{S: Square{}}",
+		"xs := []Shape{}; xs = append(xs, Square{})",
+	} {
+		vm, _ := newTestVM()
+		err := vm.Run(`
+package main
+type Shape interface { Area() float64 }
+type Square struct { Side float64 }
+type Holder struct { S Shape }
+func show(s Shape) {}
+func main() { ` + body + ` }
+`)
+		if err == nil || !strings.Contains(err.Error(), "Square does not implement Shape (missing method Area)") {
+			t.Errorf("%s: expected does-not-implement error, got %v", body, err)
+		}
+	}
+}
+
+func TestInterfaceMethodSignatures(t *testing.T) {
+	vm, _ := newTestVM()
+	err := vm.Run(`
+package main
+type Shape interface { Area() float64 }
+type Bad struct{}
+func (Bad) Area() string { return "" }
+func main() { var s Shape = Bad{}; _ = s }
+`)
+	if err == nil || !strings.Contains(err.Error(), "Bad does not implement Shape (wrong type for method Area)") {
+		t.Errorf("expected wrong-type error, got %v", err)
+	}
+	vm, _ = newTestVM()
+	err = vm.Run(`
+package main
+type Stringer interface { String() string }
+type B struct{}
+func (B) String() int { return 1 }
+func Show[T Stringer](x T) {}
+func main() { Show(B{}) }
+`)
+	if err == nil || !strings.Contains(err.Error(), "B does not satisfy Stringer") {
+		t.Errorf("expected constraint error, got %v", err)
+	}
+	out := runAndCapture(t, `
+package main
+import "fmt"
+type Shape interface { Area() float64 }
+type Bad struct{}
+func (Bad) Area() string { return "" }
+type Getter[T any] interface { Get() T }
+type Box[E any] struct { v E }
+func (b Box[E]) Get() E { return b.v }
+func Show[T interface{ Get() int }](x T) int { return x.Get() }
+func main() {
+	_, ok := any(Bad{}).(Shape)
+	fmt.Println(ok)
+	var g Getter[int] = Box[int]{3}
+	fmt.Println(g.Get(), Show(Box[int]{4}))
+}
+`)
+	if strings.TrimSpace(out) != "false\n3 4" {
+		t.Errorf("unexpected output %q", out)
+	}
+}
+
+func TestErrorInterface(t *testing.T) {
+	out := runAndCapture(t, `
+package main
+import "fmt"
+import "errors"
+type NotFound struct { Key string }
+func (e NotFound) Error() string { return "not found: " + e.Key }
+func lookup(k string) error {
+	if k == "x" { return errors.New("bad key") }
+	return NotFound{Key: k}
+}
+func main() {
+	var err error = lookup("y")
+	fmt.Println(err.Error())
+	if nf, ok := err.(NotFound); ok { fmt.Println("key", nf.Key) }
+	err = lookup("x")
+	fmt.Println(err.Error())
+}
+`)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	expected := []string{"not found: y", "key y", "bad key"}
+	for i, want := range expected {
+		if i >= len(lines) || strings.TrimSpace(lines[i]) != want {
+			t.Errorf("line %d: want %q, got %q", i, want, safeIndex(lines, i))
+		}
+	}
+}
+
+func TestGenericFunctions(t *testing.T) {
+	out := runAndCapture(t, `
+package main
+import "fmt"
+import "cmp"
+type Number interface { ~int | ~float64 }
+func Map[T, U any](s []T, f func(T) U) []U {
+	out := make([]U, 0, len(s))
+	for _, v := range s { out = append(out, f(v)) }
+	return out
+}
+func Sum[N Number](xs []N) N {
+	var total N
+	for _, x := range xs { total += x }
+	return total
+}
+func Index[T comparable](xs []T, v T) int {
+	for i, x := range xs { if x == v { return i } }
+	return -1
+}
+func Max[T cmp.Ordered](a, b T) T { if a > b { return a }; return b }
+func Keys[K comparable, V any](m map[K]V) int { return len(m) }
+func main() {
+	words := Map([]int{1, 2, 3}, func(n int) string { return fmt.Sprintf("#%d", n) })
+	fmt.Println(words, len(words))
+	fmt.Println(Sum([]int{1, 2, 3}), Sum([]float64{1.5, 2}))
+	fmt.Println(Index([]string{"a", "b"}, "b"))
+	fmt.Println(Max[int](3, 7), Max("go", "c"))
+	double := Map[int, int]
+	fmt.Println(double([]int{4}, func(n int) int { return n * 2 }))
+	fmt.Println(Keys(map[string]bool{"x": true}))
+}
+`)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	expected := []string{"[#1 #2 #3] 3", "6 3.5", "1", "7 go", "[8]", "1"}
+	for i, want := range expected {
+		if i >= len(lines) || strings.TrimSpace(lines[i]) != want {
+			t.Errorf("line %d: want %q, got %q", i, want, safeIndex(lines, i))
+		}
+	}
+}
+
+func TestGenericTypes(t *testing.T) {
+	out := runAndCapture(t, `
+package main
+import "fmt"
+type Stack[T any] struct { items []T }
+func (s *Stack[T]) Push(v T) { s.items = append(s.items, v) }
+func (s *Stack[T]) Pop() (T, bool) {
+	var zero T
+	if len(s.items) == 0 { return zero, false }
+	v := s.items[len(s.items)-1]
+	s.items = s.items[:len(s.items)-1]
+	return v, true
+}
+type Pair[K comparable, V any] struct { Key K; Val V }
+func main() {
+	s := &Stack[string]{}
+	s.Push("a"); s.Push("b")
+	v, ok := s.Pop()
+	fmt.Println(v, ok)
+	var n Stack[int]
+	z, ok := n.Pop()
+	fmt.Println(z, ok)
+	p := Pair[string, int]{Key: "x"}
+	fmt.Println(p.Key, p.Val)
+	fmt.Println(fmt.Sprintf("%T", p.Val))
+}
+`)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	expected := []string{"b true", "0 false", "x 0", "int"}
+	for i, want := range expected {
+		if i >= len(lines) || strings.TrimSpace(lines[i]) != want {
+			t.Errorf("line %d: want %q, got %q", i, want, safeIndex(lines, i))
+		}
+	}
+}
+
+func TestGenericConstraintErrors(t *testing.T) {
+	for _, c := range []struct{ body, want string }{
+		{`Sum([]string{"a"})`, "string does not satisfy ~int | ~float64"},
+		{`var p Pair[int]; _ = Pair[int]{A: p.A}; Zero()`, "cannot infer T"},
+	} {
+		vm, _ := newTestVM()
+		err := vm.Run(`
+package main
+type Pair[T any] struct { A T }
+func Sum[N ~int | ~float64](xs []N) N { var t N; return t }
+func Zero[T any]() T { var z T; return z }
+func main() { ` + c.body + ` }
+`)
+		if err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("%s: expected %q, got %v", c.body, c.want, err)
+		}
+	}
+}
+
+func TestPointers(t *testing.T) {
+	out := runAndCapture(t, `
+package main
+import "fmt"
+type Point struct { X, Y int }
+func bump(n *int) { *n = *n + 1 }
+func main() {
+	x := 1
+	p := &x
+	*p = 5
+	bump(&x)
+	fmt.Println(x, *p, p == &x)
+	pt := Point{X: 1}
+	px := &pt.X
+	*px = 9
+	q := &pt
+	q.Y = 3
+	fmt.Println(pt.X, pt.Y, q.X)
+	xs := []int{1, 2, 3}
+	e := &xs[1]
+	*e = 20
+	fmt.Println(xs)
+	n := new(int)
+	*n += 4
+	fmt.Println(*n)
+	var np *Point
+	fmt.Println(np == nil, q != nil)
+	r := &Point{X: 7}
+	fmt.Println(r.X)
+}
+`)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	expected := []string{"6 6 true", "9 3 9", "[1 20 3]", "4", "true true", "7"}
+	for i, want := range expected {
+		if i >= len(lines) || strings.TrimSpace(lines[i]) != want {
+			t.Errorf("line %d: want %q, got %q", i, want, safeIndex(lines, i))
+		}
+	}
+}
+
+func TestPointerAndValueReceivers(t *testing.T) {
+	out := runAndCapture(t, `
+package main
+import "fmt"
+type Counter struct { N int }
+func (c *Counter) Inc() { c.N++ }
+func (c Counter) IncCopy() { c.N++ }
+func (c *Counter) Reset() { *c = Counter{} }
+func (c Counter) Get() int { return c.N }
+func main() {
+	var c Counter
+	c.Inc(); c.Inc()
+	c.IncCopy()
+	fmt.Println(c.N, c.Get())
+	p := &c
+	p.Inc()
+	p.IncCopy()
+	fmt.Println(c.N, p.Get())
+	p.Reset()
+	fmt.Println(c.N)
+	cs := []Counter{Counter{}, Counter{}}
+	cs[1].Inc()
+	fmt.Println(cs[1].N)
+}
+`)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	expected := []string{"2 2", "3 3", "0", "1"}
+	for i, want := range expected {
+		if i >= len(lines) || strings.TrimSpace(lines[i]) != want {
+			t.Errorf("line %d: want %q, got %q", i, want, safeIndex(lines, i))
+		}
+	}
+}
+
+func TestNilPointerDereferencePanics(t *testing.T) {
+	for _, body := range []string{"var p *int; _ = *p", "var p *Point; p.X = 1", "var p *Point; _ = p.X"} {
+		vm, _ := newTestVM()
+		err := vm.Run(`
+package main
+type Point struct { X int }
+func main() { ` + body + ` }
+`)
+		if err == nil || !strings.Contains(err.Error(), "invalid memory address or nil pointer dereference") {
+			t.Errorf("%s: expected nil dereference panic, got %v", body, err)
+		}
+	}
+}
+
+func TestPointerReceiverMethodSet(t *testing.T) {
+	vm, _ := newTestVM()
+	err := vm.Run(`
+package main
+type Shape interface { Area() float64 }
+type Square struct { Side float64 }
+func (s *Square) Area() float64 { return s.Side * s.Side }
+func main() {
+	var ok Shape = &Square{Side: 2}
+	_ = ok
+	var bad Shape = Square{Side: 2}
+	_ = bad
+}
+`)
+	if err == nil || !strings.Contains(err.Error(), "Square does not implement Shape (method Area has pointer receiver)") {
+		t.Fatalf("expected pointer receiver error, got %v", err)
+	}
+}
+
+func TestStructValueSemantics(t *testing.T) {
+	out := runAndCapture(t, `
+package main
+import "fmt"
+type Inner struct { N int }
+type Point struct { X int; In Inner }
+func mutate(p Point) { p.X = 100; p.In.N = 100 }
+func main() {
+	a := Point{X: 1}
+	b := a
+	b.X = 2
+	b.In.N = 2
+	fmt.Println(a.X, a.In.N, b.X, b.In.N)
+	mutate(a)
+	fmt.Println(a.X, a.In.N)
+	ps := []Point{a}
+	a.X = 3
+	fmt.Println(ps[0].X)
+	for _, p := range ps { p.X = 50 }
+	fmt.Println(ps[0].X)
+	ps[0].X = 7
+	fmt.Println(ps[0].X)
+	m := map[string]Point{}
+	m["a"] = a
+	a.X = 4
+	fmt.Println(m["a"].X)
+	ch := make(chan Point, 1)
+	ch <- a
+	a.X = 5
+	got := <-ch
+	fmt.Println(got.X)
+	p := &a
+	p.X = 6
+	fmt.Println(a.X)
+}
+`)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	expected := []string{"1 0 2 2", "1 0", "1", "1", "7", "3", "4", "6"}
+	for i, want := range expected {
+		if i >= len(lines) || strings.TrimSpace(lines[i]) != want {
+			t.Errorf("line %d: want %q, got %q", i, want, safeIndex(lines, i))
+		}
+	}
+}
+
+func TestArrays(t *testing.T) {
+	out := runAndCapture(t, `
+package main
+import "fmt"
+const Size = 3
+type Board struct { Cells [Size][Size]int }
+func fill(a [3]int) { a[0] = 99 }
+func main() {
+	var a [3]int
+	a[1] = 5
+	fmt.Println(a, len(a))
+	b := a
+	b[0] = 1
+	fill(a)
+	fmt.Println(a, b, a == b, a == [3]int{0, 5, 0})
+	c := [...]string{"x", "y"}
+	d := [5]int{2: 7, 4: 9}
+	fmt.Println(len(c), d)
+	var bd Board
+	bd.Cells[1][2] = 4
+	cp := bd
+	cp.Cells[1][2] = 8
+	fmt.Println(bd.Cells[1], cp.Cells[1])
+	s := d[1:3]
+	s[0] = 42
+	fmt.Println(d, cap(d))
+	p := &d
+	p[4] = 1
+	nz := []int{}
+	for i, v := range p { if v != 0 { nz = append(nz, i) } }
+	fmt.Println(nz)
+	seen := map[[2]int]bool{}
+	seen[[2]int{1, 2}] = true
+	fmt.Println(seen[[2]int{1, 2}], seen[[2]int{2, 1}])
+}
+`)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	expected := []string{"[0 5 0] 3", "[0 5 0] [1 5 0] false true", "2 [0 0 7 0 9]", "[0 0 4] [0 0 8]", "[0 42 7 0 9] 5", "[1 2 4]", "true false"}
+	for i, want := range expected {
+		if i >= len(lines) || strings.TrimSpace(lines[i]) != want {
+			t.Errorf("line %d: want %q, got %q", i, want, safeIndex(lines, i))
+		}
+	}
+}
+
+func TestRecover(t *testing.T) {
+	out := runAndCapture(t, `
+package main
+import "fmt"
+func safeDiv(xs []int, i int) int {
+	defer func() {
+		if r := recover(); r != nil { fmt.Println("recovered:", r) }
+	}()
+	return xs[i]
+}
+func helper() any { return recover() }
+func indirect() {
+	defer func() {
+		if r := recover(); r != nil { fmt.Println("outer got", r) }
+	}()
+	func() {
+		defer func() { fmt.Println("helper got", helper()) }()
+		panic("boom")
+	}()
+}
+func order() {
+	defer fmt.Println("first deferred, last run")
+	defer func() { fmt.Println("recover:", recover()) }()
+	defer fmt.Println("runs while panicking")
+	panic("stop")
+}
+func main() {
+	fmt.Println(safeDiv([]int{1, 2}, 5))
+	fmt.Println(recover())
+	indirect()
+	order()
+	fmt.Println("done")
+}
+`)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	expected := []string{
+		"recovered: runtime error: index out of range [5] with length 2", "0", "<nil>",
+		"helper got <nil>", "outer got boom",
+		"runs while panicking", "recover: stop", "first deferred, last run", "done",
+	}
+	for i, want := range expected {
+		if i >= len(lines) || strings.TrimSpace(lines[i]) != want {
+			t.Errorf("line %d: want %q, got %q", i, want, safeIndex(lines, i))
+		}
+	}
+}
+
+func TestPanicInDeferredCallReplacesPanic(t *testing.T) {
+	vm, _ := newTestVM()
+	err := vm.Run(`
+package main
+func main() {
+	defer func() { panic("second") }()
+	panic("first")
+}
+`)
+	if err == nil || !strings.Contains(err.Error(), "panic: second") {
+		t.Fatalf("expected second panic to propagate, got %v", err)
+	}
+}
+
+func TestLabelsGotoAndFallthrough(t *testing.T) {
+	out := runAndCapture(t, `
+package main
+import "fmt"
+func main() {
+	pairs := 0
+outer:
+	for i := 0; i < 4; i++ {
+		for j := 0; j < 4; j++ {
+			if j > i { continue outer }
+			if i == 3 { break outer }
+			pairs++
+		}
+	}
+	fmt.Println("pairs", pairs)
+	n := 0
+loop:
+	for {
+		switch {
+		case n >= 3: break loop
+		default: n++
+		}
+	}
+	fmt.Println("n", n)
+	ch := make(chan int, 1)
+	got := 0
+recv:
+	for {
+		ch <- got
+		select {
+		case v := <-ch:
+			if v == 2 { break recv }
+			got++
+		}
+	}
+	fmt.Println("got", got)
+	i := 0
+again:
+	if i < 3 { i++; goto again }
+	fmt.Println("goto", i)
+	for _, v := range []int{1, 2, 3} {
+		switch v {
+		default: fmt.Println("default", v)
+		case 1: fmt.Println("one")
+			fallthrough
+		case 2: fmt.Println("one or two", v)
+		}
+	}
+	for k := 0; k < 3; k++ {
+		switch k { case 1: break }
+		fmt.Println("after switch", k)
+	}
+}
+`)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	expected := []string{
+		"pairs 6", "n 3", "got 2", "goto 3",
+		"one", "one or two 1", "one or two 2", "default 3",
+		"after switch 0", "after switch 1", "after switch 2",
+	}
+	for i, want := range expected {
+		if i >= len(lines) || strings.TrimSpace(lines[i]) != want {
+			t.Errorf("line %d: want %q, got %q", i, want, safeIndex(lines, i))
+		}
+	}
+}
+
+func TestNamedResults(t *testing.T) {
+	out := runAndCapture(t, `
+package main
+import (
+	"errors"
+	"fmt"
+)
+func sumdiff(a, b int) (s, d int) {
+	s = a + b
+	d = a - b
+	return
+}
+func swap(a, b string) (x, y string) { return b, a }
+func pair() (int, int) { return 1, 2 }
+func forward() (a, b int) { return pair() }
+func counter() (n int) {
+	defer func() { n *= 10 }()
+	n = 4
+	return n + 1
+}
+func wrap() (err error) {
+	defer func() {
+		if err != nil { err = fmt.Errorf("wrap: %v", err) }
+	}()
+	return errors.New("inner")
+}
+func safe() (res string, err error) {
+	defer func() {
+		if r := recover(); r != nil { err = fmt.Errorf("recovered %v", r) }
+	}()
+	res = "partial"
+	panic("bad")
+}
+func zero() (s string, ok bool) { return }
+func main() {
+	fmt.Println(sumdiff(17, 5))
+	fmt.Println(swap("a", "b"))
+	fmt.Println(forward())
+	fmt.Println(counter())
+	fmt.Println(wrap())
+	fmt.Println(safe())
+	s, ok := zero()
+	fmt.Println(len(s), ok)
+}
+`)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	expected := []string{"22 12", "b a", "1 2", "50", "wrap: inner", "partial recovered bad", "0 false"}
+	for i, want := range expected {
+		if i >= len(lines) || strings.TrimSpace(lines[i]) != want {
+			t.Errorf("line %d: want %q, got %q", i, want, safeIndex(lines, i))
+		}
+	}
+}
+
+func TestStructEmbedding(t *testing.T) {
+	out := runAndCapture(t, `
+package main
+import "fmt"
+type User struct {
+	Name string
+	Logins int
+}
+func (u User) Greet() string { return "hi " + u.Name }
+func (u *User) Login() { u.Logins++ }
+type Admin struct {
+	User
+	Level int
+}
+type Root struct {
+	*Admin
+	Shell string
+}
+type Greeter interface { Greet() string }
+type Logger interface { Login() }
+type GreetLogger interface {
+	Greeter
+	Logger
+}
+func main() {
+	a := Admin{User: User{Name: "ann"}, Level: 2}
+	fmt.Println(a.Name, a.Level, a.User.Name)
+	a.Name = "amy"
+	a.Login()
+	a.Login()
+	fmt.Println(a.User.Name, a.Logins)
+	fmt.Println(a.Greet())
+	var g Greeter = a
+	fmt.Println(g.Greet())
+	var gl GreetLogger = &a
+	gl.Login()
+	fmt.Println(a.Logins)
+	r := Root{Admin: &a, Shell: "sh"}
+	r.Login()
+	fmt.Println(r.Name, r.Logins, r.Level, r.Greet())
+	var l Logger = r
+	l.Login()
+	fmt.Println(a.Logins)
+	b := a
+	b.Name = "bob"
+	fmt.Println(a.Name, b.Name)
+}
+`)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	expected := []string{"ann 2 ann", "amy 2", "hi amy", "hi amy", "3", "amy 4 2 hi amy", "5", "amy bob"}
+	for i, want := range expected {
+		if i >= len(lines) || strings.TrimSpace(lines[i]) != want {
+			t.Errorf("line %d: want %q, got %q", i, want, safeIndex(lines, i))
+		}
+	}
+}
+
+func TestPromotedPointerMethodNeedsAddress(t *testing.T) {
+	vm, _ := newTestVM()
+	err := vm.Run(`
+package main
+type User struct{ n int }
+func (u *User) Login() { u.n++ }
+type Admin struct{ User }
+type Logger interface { Login() }
+func main() {
+	var l Logger = Admin{}
+	l.Login()
+}
+`)
+	if err == nil || !strings.Contains(err.Error(), "method Login has pointer receiver") {
+		t.Fatalf("expected pointer receiver error, got %v", err)
+	}
+}
+
+func TestCompositeLiterals(t *testing.T) {
+	out := runAndCapture(t, `
+package main
+import "fmt"
+type Point struct{ X, Y int }
+type Line struct {
+	From, To Point
+	Tag *Point
+}
+func main() {
+	p := Point{1, 2}
+	fmt.Println(p.X, p.Y)
+	ps := []Point{{1, 2}, {3, 4}, {Y: 6}}
+	fmt.Println(len(ps), ps[1].X, ps[2].X, ps[2].Y)
+	m := map[string]Point{"a": {5, 6}}
+	fmt.Println(m["a"].Y)
+	keys := map[Point]string{{1, 2}: "p"}
+	fmt.Println(keys[p])
+	ptrs := []*Point{{7, 8}, {}}
+	ptrs[1].X = 9
+	fmt.Println(ptrs[0].Y, ptrs[1].X)
+	l := Line{Point{0, 0}, Point{1, 1}, &Point{2, 2}}
+	fmt.Println(l.To.X, l.Tag.Y)
+	grid := [2][2]int{{1, 2}, {3, 4}}
+	fmt.Println(grid[1][0])
+	anon := struct {
+		Name string
+		Age  int
+	}{"ann", 30}
+	fmt.Println(anon.Name, anon.Age)
+	people := []struct{ Name string }{{"a"}, {"b"}}
+	fmt.Println(people[1].Name)
+	a, b := &Point{}, &Point{}
+	c := a
+	fmt.Println(a == b, a == c)
+}
+`)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	expected := []string{"1 2", "3 3 0 6", "6", "p", "8 9", "1 2", "3", "ann 30", "b", "false true"}
+	for i, want := range expected {
+		if i >= len(lines) || strings.TrimSpace(lines[i]) != want {
+			t.Errorf("line %d: want %q, got %q", i, want, safeIndex(lines, i))
+		}
+	}
+}
+
+func TestCompositeLiteralErrors(t *testing.T) {
+	cases := map[string]string{
+		`p := Point{Z: 1}`:    "unknown field Z in struct literal of type Point",
+		`p := Point{1, 2, 3}`: "too many values in struct literal of type Point",
+		`p := Point{1}`:       "too few values in struct literal of type Point",
+		`p := Point{X: 1, 2}`: "mixture of field:value and value elements",
+	}
+	for body, want := range cases {
+		vm, _ := newTestVM()
+		err := vm.Run("package main\ntype Point struct{ X, Y int }\nfunc main() {\n\t" + body + "\n\t_ = p\n}\n")
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: expected %q, got %v", body, want, err)
+		}
+	}
+}
+
+func TestSizedNumericTypes(t *testing.T) {
+	out := runAndCapture(t, `
+package main
+import "fmt"
+func fnv(data []byte) uint32 {
+	var h uint32 = 2166136261
+	for _, b := range data {
+		h ^= uint32(b)
+		h *= 16777619
+	}
+	return h
+}
+func half(x uint8) uint8 { return x >> 1 }
+func main() {
+	var x uint8 = 250
+	x += 10
+	var i8 int8 = 127
+	i8++
+	var u uint
+	u--
+	w := uint16(65535)
+	w++
+	fmt.Println(x, i8, u, w)
+	var s int16 = -8
+	fmt.Println(s >> 1, uint16(s) >> 1)
+	v, m, f := 200, -1, 3.9
+	fmt.Println(int8(v), uint8(m), int(f), int(-f))
+	fmt.Println(fnv([]byte{104, 105}))
+	fmt.Println(fmt.Sprintf("%T %T %T", x, half(9), float32(1)))
+	fmt.Println(^uint8(0), -x, ^0)
+	c := complex(1, 2)
+	fmt.Println(c*c, real(c), imag(c))
+	keys := map[uint8]string{1: "one"}
+	fmt.Println(keys[1])
+	var b byte = 7
+	var r rune = 120
+	var anyv interface{} = b
+	_, isByte := anyv.(byte)
+	fmt.Println(isByte, r, fmt.Sprintf("%T", r))
+}
+`)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	expected := []string{
+		"4 -128 18446744073709551615 0", "-4 32764", "-56 255 3 -3", "1748694682",
+		"uint8 uint8 float32", "255 252 -1", "(-3+4i) 1 2", "one", "true 120 int32",
+	}
+	for i, want := range expected {
+		if i >= len(lines) || strings.TrimSpace(lines[i]) != want {
+			t.Errorf("line %d: want %q, got %q", i, want, safeIndex(lines, i))
+		}
+	}
+}
+
+func TestIntegerDivision(t *testing.T) {
+	out := runAndCapture(t, `
+package main
+import "fmt"
+func div(a, b int) (q int, err error) {
+	defer func() {
+		if r := recover(); r != nil { err = fmt.Errorf("%v", r) }
+	}()
+	return a / b, nil
+}
+func main() {
+	fmt.Println(7 / 2, -7 / 2, 7 % 3, -7 % 3)
+	fmt.Println(7.0 / 2, 7 / 2.0)
+	var u uint8 = 200
+	fmt.Println(u / 3, u % 7)
+	n := 10
+	n /= 4
+	fmt.Println(n)
+	fmt.Println(div(9, 3))
+	fmt.Println(div(1, 0))
+	x := 0
+	func() {
+		defer func() { fmt.Println("recovered:", recover()) }()
+		fmt.Println(5 % x)
+	}()
+}
+`)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	expected := []string{
+		"3 -3 1 -1", "3.5 3.5", "66 4", "2", "3 <nil>",
+		"0 runtime error: integer divide by zero", "recovered: runtime error: integer divide by zero",
+	}
+	for i, want := range expected {
+		if i >= len(lines) || strings.TrimSpace(lines[i]) != want {
+			t.Errorf("line %d: want %q, got %q", i, want, safeIndex(lines, i))
+		}
+	}
+}
+
+func TestConstantsAndIota(t *testing.T) {
+	out := runAndCapture(t, `
+package main
+import (
+	"fmt"
+	"math"
+)
+type Weekday int
+const (
+	Sunday Weekday = iota
+	Monday
+	Tuesday
+)
+const (
+	_ = iota
+	KB = 1 << (10 * iota)
+	MB
+	GB
+)
+const (
+	A, B = iota, iota * 10
+	C, D
+)
+const big = 1 << 100
+const small = big >> 98
+const X uint8 = 200
+func main() {
+	fmt.Println(Sunday, Monday, Tuesday)
+	fmt.Println(KB, MB, GB)
+	fmt.Println(A, B, C, D)
+	fmt.Println(small, 1 << 62)
+	fmt.Println(fmt.Sprintf("%T %v", X, X + 50))
+	fmt.Println(math.MaxInt64, uint64(math.MaxUint64), math.MinInt8)
+	const h = 1 / 2
+	const f = 1 / 2.0
+	fmt.Println(h, f)
+	const c = 'a' + 1
+	fmt.Println(c, len("héllo"))
+	var m uint8 = ^uint8(0)
+	fmt.Println(m, big / (1 << 99))
+}
+`)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	expected := []string{
+		"0 1 2", "1024 1048576 1073741824", "0 0 1 10", "4 4611686018427387904",
+		"uint8 250", "9223372036854775807 18446744073709551615 -128", "0 0.5", "98 6", "255 2",
+	}
+	for i, want := range expected {
+		if i >= len(lines) || strings.TrimSpace(lines[i]) != want {
+			t.Errorf("line %d: want %q, got %q", i, want, safeIndex(lines, i))
+		}
+	}
+}
+
+func TestConstantErrors(t *testing.T) {
+	cases := map[string]string{
+		"const x int8 = 300":                     "constant 300 overflows int8",
+		"const x = 1 << 64\nvar y = x":           "constant 18446744073709551616 overflows int",
+		"const x = 1 / 0":                        "division by zero",
+		"const (\n\tx, y = 1\n)":                 "missing init expr",
+		"func f() {\n\tconst x = 1\n\tx = 2\n}":  "cannot assign to x",
+		"var i8 int8 = 200":                      "constant 200 overflows int8",
+		"var u uint = -1":                        "constant -1 overflows uint",
+		"func f() {\n\tx := 1 << 63\n\t_ = x\n}": "constant 9223372036854775808 overflows int",
+		"import \"math\"\nfunc f() {\n\tx := math.MaxUint64\n\t_ = x\n}": "constant 18446744073709551615 overflows int",
+		"func f() {\n\tvar b uint8\n\tb = 256\n}":                        "constant 256 overflows uint8",
+	}
+	for decl, want := range cases {
+		vm, _ := newTestVM()
+		err := vm.Run("package main\n" + decl + "\nfunc main() { f() }\n")
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: expected %q, got %v", decl, want, err)
+		}
+	}
+}
+
+func TestLiterals(t *testing.T) {
+	out := runAndCapture(t, `
+package main
+import "fmt"
+func main() {
+	fmt.Println(0xFF, 0b1010, 0o17, 017, 1_000_000, 1e3, 0x1p4, .5)
+	fmt.Println("a\tb")
+	fmt.Println(` + "`raw\\n`" + `, "é\x41\101\"")
+	fmt.Println('a', '\n', '\'', 'é', '\x7f')
+	fmt.Println(fmt.Sprintf("%T %T %T", 'x', 2i, 1.5))
+	fmt.Println(2i * 2i, 1 + 2i)
+}
+`)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	expected := []string{
+		"255 10 15 15 1000000 1000 16 0.5", "a\tb", `raw\n éAA"`, "97 10 39 233 127",
+		"int32 complex128 float64", "(-4+0i) (1+2i)",
+	}
+	for i, want := range expected {
+		if i >= len(lines) || strings.TrimSpace(lines[i]) != want {
+			t.Errorf("line %d: want %q, got %q", i, want, safeIndex(lines, i))
+		}
+	}
+}
+
+func TestUnicodeStrings(t *testing.T) {
+	out := runAndCapture(t, `
+package main
+import (
+	"fmt"
+	"unicode"
+	"unicode/utf8"
+)
+func main() {
+	s := "héllo, 世界"
+	fmt.Println(len(s), utf8.RuneCountInString(s), len([]rune(s)))
+	for i, r := range "aé世" { fmt.Println(i, r, string(r)) }
+	fmt.Println(fmt.Sprintf("%T %v %T", s[1], s[0], []byte(s)[0]))
+	b := []byte("hi")
+	b[0] = 'H'
+	fmt.Println(string(b), b)
+	r := []rune(s)
+	r[0] = unicode.ToUpper(r[0])
+	fmt.Println(string(r), string(r[7:]), string(rune(65)))
+	n := 0
+	for i := 0; i < len(s); i++ { if s[i] == 'l' { n++ } }
+	fmt.Println(n, unicode.IsLetter('x'), unicode.IsDigit('x'), unicode.IsSpace(' '))
+	c, size := utf8.DecodeRuneInString("世界")
+	fmt.Println(c, size, utf8.RuneLen('é'), utf8.ValidString(s), utf8.RuneError)
+	ab := append([]byte("ab"), "cd"...)
+	zb := []byte("abc")
+	fmt.Println(string(ab), len(ab), copy(zb, "zz"), string(zb))
+}
+`)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	expected := []string{
+		"14 9 9", "0 97 a", "1 233 é", "3 19990 世", "uint8 104 uint8", "Hi [72 105]",
+		"Héllo, 世界 世界 A", "2 true false true", "19990 3 2 true 65533", "abcd 4 2 zzc",
+	}
+	for i, want := range expected {
+		if i >= len(lines) || strings.TrimSpace(lines[i]) != want {
+			t.Errorf("line %d: want %q, got %q", i, want, safeIndex(lines, i))
+		}
+	}
+}
+
+func TestNamedTypes(t *testing.T) {
+	out := runAndCapture(t, `
+package main
+import "fmt"
+type Celsius float64
+type Fahrenheit float64
+func (c Celsius) ToF() Fahrenheit { return Fahrenheit(c*9/5 + 32) }
+type Weekday int
+const (
+	Sunday Weekday = iota
+	Monday
+	Tuesday
+)
+func (d Weekday) String() string { return [...]string{"Sun", "Mon", "Tue"}[d] }
+func (d Weekday) Next() Weekday { return (d + 1) % 3 }
+type Counter int
+func (c *Counter) Inc() { *c++ }
+type Handler func(string) string
+func (h Handler) Twice(s string) string { return h(h(s)) }
+type Stack []int
+func (s *Stack) Push(v int) { *s = append(*s, v) }
+func (s Stack) Top() int { return s[len(s)-1] }
+type Set map[string]bool
+type Temp = Celsius
+type Number = int
+func main() {
+	c := Celsius(100)
+	fmt.Println(c.ToF(), float64(c) + 1, c > 50)
+	var t Temp = 37.5
+	fmt.Println(t.ToF(), fmt.Sprintf("%.1f", -t))
+	fmt.Println(Monday, Monday.Next(), fmt.Sprintf("%v|%d|%s", Monday, Monday, Sunday))
+	var n Counter
+	n.Inc(); n.Inc()
+	fmt.Println(n, int(n) * 10)
+	up := Handler(func(s string) string { return s + "!" })
+	fmt.Println(up("hi"), up.Twice("go"))
+	var s Stack
+	s.Push(1); s.Push(2)
+	fmt.Println(len(s), s.Top(), s[0], s[1:])
+	set := Set{"a": true}
+	set["b"] = true
+	fmt.Println(len(set), set["a"], set["z"])
+	var x Number = 4
+	fmt.Println(x * 2)
+	var v any = Monday
+	switch w := v.(type) {
+	case int: fmt.Println("int", w)
+	case Weekday: fmt.Println("weekday", w)
+	}
+	_, isInt := v.(int)
+	fmt.Println(isInt)
+}
+`)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	expected := []string{
+		"212 101 true", "99.5 -37.5", "Mon Tue Mon|1|Sun", "2 20", "hi! go!!",
+		"2 2 1 [2]", "2 true false", "8", "weekday Mon", "false",
+	}
+	for i, want := range expected {
+		if i >= len(lines) || strings.TrimSpace(lines[i]) != want {
+			t.Errorf("line %d: want %q, got %q", i, want, safeIndex(lines, i))
+		}
+	}
+}
+
+func TestMethodValuesAndFuncFields(t *testing.T) {
+	out := runAndCapture(t, `
+package main
+import "fmt"
+type Counter struct{ n int }
+func (c *Counter) Add(d int) int { c.n += d; return c.n }
+func (c Counter) Get() int { return c.n }
+type Button struct {
+	Label   string
+	OnClick func(string) string
+}
+type Shape interface{ Area() int }
+type Sq struct{ s int }
+func (q Sq) Area() int { return q.s * q.s }
+func apply(f func(int) int, v int) int { return f(v) }
+func main() {
+	c := &Counter{}
+	add := c.Add
+	add(2); add(3)
+	get := c.Get
+	c.Add(10)
+	fmt.Println(c.n, get(), apply(c.Add, 1))
+	inc := (*Counter).Add
+	fmt.Println(inc(c, 4), Counter.Get(*c))
+	area := Shape.Area
+	fmt.Println(area(Sq{3}), Sq.Area(Sq{2}))
+	b := Button{Label: "ok", OnClick: func(s string) string { return "clicked " + s }}
+	fmt.Println(b.OnClick(b.Label))
+	pb := &b
+	pb.OnClick = func(s string) string { return "changed " + s }
+	fmt.Println(b.OnClick("x"))
+	handlers := []func() int{c.Get, Sq{5}.Area}
+	for _, h := range handlers { fmt.Println(h()) }
+	defer func() { fmt.Println("recovered:", recover()) }()
+	var e Button
+	e.OnClick("boom")
+}
+`)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	expected := []string{
+		"15 5 16", "20 20", "9 4", "clicked ok", "changed x", "20", "25",
+		"recovered: runtime error: invalid memory address or nil pointer dereference",
+	}
+	for i, want := range expected {
+		if i >= len(lines) || strings.TrimSpace(lines[i]) != want {
+			t.Errorf("line %d: want %q, got %q", i, want, safeIndex(lines, i))
+		}
+	}
+}
+
+func TestMethodExpressionNeedsPointer(t *testing.T) {
+	vm, _ := newTestVM()
+	err := vm.Run("package main\ntype T struct{}\nfunc (t *T) M() {}\nfunc main() { f := T.M; _ = f }\n")
+	if err == nil || !strings.Contains(err.Error(), "needs pointer receiver") {
+		t.Fatalf("expected pointer receiver error, got %v", err)
+	}
+}
+
+func TestLoopVariablesPerIteration(t *testing.T) {
+	out := runAndCapture(t, `package main
+import ("fmt"; "sync")
+type Small uint8
+func main() {
+	var fs []func() int
+	for i := 0; i < 3; i++ { fs = append(fs, func() int { return i }) }
+	for _, v := range []string{"a", "b"} { fs = append(fs, func() int { return len(v) * 10 }) }
+	var got []int
+	for _, f := range fs { got = append(got, f()) }
+	fmt.Println(got)
+	var ptrs []*int
+	for i := 0; i < 3; i++ { ptrs = append(ptrs, &i); i++ }
+	fmt.Println(*ptrs[0], *ptrs[1])
+	var wg sync.WaitGroup
+	res := make([]int, 3)
+	for i := range 3 { wg.Add(1); go func() { defer wg.Done(); res[i] = i * i }() }
+	wg.Wait()
+	fmt.Println(res)
+	var k, v int
+	for k, v = range []int{7, 8, 9} {}
+	fmt.Println(k, v)
+	n := 0
+	for range 4 { n++ }
+	var s Small = 2
+	for i := range s { fmt.Println(i, i < s) }
+	for i := range uint8(2) { fmt.Println(fmt.Sprintf("%T", i), n) }
+}
+`)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	expected := []string{"[0 1 2 10 10]", "1 3", "[0 1 4]", "2 9", "0 true", "1 true", "uint8 4", "uint8 4"}
+	for i, want := range expected {
+		if i >= len(lines) || strings.TrimSpace(lines[i]) != want {
+			t.Errorf("line %d: want %q, got %q", i, want, safeIndex(lines, i))
+		}
+	}
+}
+
+func TestRangeOverFunc(t *testing.T) {
+	out := runAndCapture(t, `package main
+import ("fmt"; "iter"; "maps"; "slices")
+type List struct{ items []string }
+func (l *List) All() iter.Seq2[int, string] {
+	return func(yield func(int, string) bool) {
+		for i, s := range l.items { if !yield(i, s) { return } }
+	}
+}
+func Count(n int) iter.Seq[int] {
+	return func(yield func(int) bool) {
+		defer fmt.Println("done")
+		for i := 0; i < n; i++ { if !yield(i) { return } }
+	}
+}
+func find(seq iter.Seq[int], x int) bool {
+	for v := range seq { if v == x { return true } }
+	return false
+}
+func main() {
+	for i, s := range (&List{items: []string{"a", "b"}}).All() { fmt.Println(i, s) }
+	for v := range Count(10) {
+		if v == 1 { continue }
+		if v == 3 { break }
+		fmt.Println("v", v)
+	}
+	fmt.Println(find(Count(5), 2))
+	m := map[string]int{"x": 1, "y": 2, "z": 3}
+	fmt.Println(slices.Sorted(maps.Keys(m)), slices.Sorted(maps.Values(m)))
+	for i, v := range slices.Backward([]int{4, 5}) { fmt.Println(i, v) }
+	m2 := maps.Collect(slices.All([]string{"p", "q"}))
+	fmt.Println(m2[1], len(m2))
+	bad := func(yield func(int) bool) { yield(1); yield(2) }
+	defer func() { fmt.Println("recovered:", recover()) }()
+	for range bad { break }
+}
+`)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	expected := []string{
+		"0 a", "1 b", "v 0", "v 2", "done", "done", "true", "[x y z] [1 2 3]", "1 5", "0 4", "q 2",
+		"recovered: runtime error: range function continued iteration after function for loop body returned false",
+	}
+	for i, want := range expected {
+		if i >= len(lines) || strings.TrimSpace(lines[i]) != want {
+			t.Errorf("line %d: want %q, got %q", i, want, safeIndex(lines, i))
+		}
+	}
+}
+
+func TestVariadicForwarding(t *testing.T) {
+	out := runAndCapture(t, `package main
+import "fmt"
+type Number interface{ ~int | ~float64 }
+func Sum[T Number](xs ...T) T { var t T; for _, x := range xs { t += x }; return t }
+func zero(xs ...int) { for i := range xs { xs[i] = 0 } }
+func avg(xs ...float64) float64 { return Sum(xs...) / float64(len(xs)) }
+func count(xs ...any) int { return len(xs) }
+type Logger struct{ prefix string }
+func (l Logger) Log(parts ...string) { fmt.Println(l.prefix, len(parts), parts) }
+func main() {
+	s := []int{1, 2, 3}
+	zero(s...)
+	fmt.Println(s, Sum(4, 5), Sum([]float64{1.5, 2}...))
+	fmt.Println(avg(1, 2), count(1, "a", nil), count())
+	l := Logger{"log:"}
+	words := []string{"a", "b"}
+	l.Log(words...)
+	defer l.Log(words...)
+	defer fmt.Println(words...)
+	words[0] = "z"
+	done := make(chan bool)
+	go func(xs ...int) { fmt.Println("go", xs); done <- true }(s...)
+	<-done
+}
+`)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	expected := []string{"[0 0 0] 9 3.5", "1.5 3 0", "log: 2 [a b]", "go [0 0 0]", "z b", "log: 2 [z b]"}
+	for i, want := range expected {
+		if i >= len(lines) || strings.TrimSpace(lines[i]) != want {
+			t.Errorf("line %d: want %q, got %q", i, want, safeIndex(lines, i))
+		}
+	}
+}
+
+func TestPackageInitOrder(t *testing.T) {
+	out := runAndCapture(t, `package main
+import "fmt"
+var total = sum(table)
+var table = build()
+var order []string
+func build() []int {
+	out := []int{}
+	for i := range size { out = append(out, i*i) }
+	return out
+}
+var size = 4
+func sum(xs []int) int { s := 0; for _, x := range xs { s += x }; return s }
+type Greeter struct{}
+func (Greeter) Hello() string { return prefix + "!" }
+var greeting = Greeter{}.Hello()
+var prefix = trace("prefix", "hi")
+func trace(name, v string) string { order = append(order, name); return v }
+var a = trace("a", b)
+var b = trace("b", "B")
+func init() { order = append(order, "init1") }
+func init() { order = append(order, "init2"); fmt.Println("init sees", total) }
+func main() { fmt.Println(total, table, greeting, a, order) }
+`)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	expected := []string{"init sees 14", "14 [0 1 4 9] hi! B [prefix b a init1 init2]"}
+	for i, want := range expected {
+		if i >= len(lines) || strings.TrimSpace(lines[i]) != want {
+			t.Errorf("line %d: want %q, got %q", i, want, safeIndex(lines, i))
+		}
+	}
+}
+
+func TestShortVarDeclScoping(t *testing.T) {
+	out := runAndCapture(t, `package main
+import ("fmt"; "strconv")
+const limit = 3
+func main() {
+	a, err := strconv.Atoi("1")
+	b, err := strconv.Atoi("x")
+	fmt.Println(a, b, err != nil)
+	x := 1
+	if true { x := 2; x++; _ = x }
+	limit := 10
+	_, y := 0, 5
+	_ = 7
+	fmt.Println(x, limit, y)
+}
+`)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	expected := []string{"1 0 true", "1 10 5"}
+	for i, want := range expected {
+		if i >= len(lines) || strings.TrimSpace(lines[i]) != want {
+			t.Errorf("line %d: want %q, got %q", i, want, safeIndex(lines, i))
+		}
+	}
+}
+
+func TestPackageInitCycle(t *testing.T) {
+	cases := map[string]string{
+		"var a = b + 1\nvar b = f()\nfunc f() int { return a }": "initialization cycle: a refers to b, b refers to a",
+		"var x = g()\nfunc g() int { return x }":                "initialization cycle: x refers to itself",
+	}
+	for decls, want := range cases {
+		vm, _ := newTestVM()
+		err := vm.Run("package main\n" + decls + "\nfunc main() {}\n")
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: expected %q, got %v", decls, want, err)
+		}
+	}
+}
+
+func TestIfInitScope(t *testing.T) {
+	out := runAndCapture(t, `package main
+import "fmt"
+func main() {
+	m := map[string]int{"a": 1}
+	if v, ok := m["a"]; ok { fmt.Println(v, ok) }
+	v, ok := m["b"]
+	fmt.Println(v, ok)
+	x := 1
+	if x := 2; x > 1 { fmt.Println(x) } else if y := x; y > 0 { fmt.Println(y) }
+	fmt.Println(x)
+}`)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	expected := []string{"1 true", "0 false", "2", "1"}
+	for i, want := range expected {
+		if i >= len(lines) || strings.TrimSpace(lines[i]) != want {
+			t.Errorf("line %d: want %q, got %q", i, want, safeIndex(lines, i))
+		}
+	}
+}
+
+func TestScopingErrors(t *testing.T) {
+	cases := map[string]string{
+		"count := 0\n\tcoutn = 1\n\t_ = count": "undefined: coutn",
+		"x := 1\n\tx := 2\n\t_ = x":            "no new variables on left side of :=",
+		"a, a := 1, 2\n\t_ = a":                "a repeated on left side of :=",
+		"if true { y := 1; _ = y }\n\ty = 2":    "undefined: y",
+		"if z := 1; z > 0 {}\n\tz = 2":          "undefined: z",
+		"for _, v = range []int{1} {}":         "undefined: v",
+	}
+	for body, want := range cases {
+		vm, _ := newTestVM()
+		err := vm.Run("package main\nfunc main() {\n\t" + body + "\n}\n")
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: expected %q, got %v", body, want, err)
+		}
+	}
+}
+
+func TestNativesReturnErrors(t *testing.T) {
+	out := runAndCapture(t, `package main
+import ("fmt"; "regexp"; json "encoding/json"; "text/template")
+func main() {
+	b, err := json.Marshal(map[string]any{"v": 1})
+	fmt.Println(b, err == nil)
+	var m map[string]any
+	err = json.Unmarshal(b, &m)
+	fmt.Println(m["v"], err == nil, json.Unmarshal("{", &m) != nil)
+	_, err = regexp.Compile("(")
+	rx, _ := regexp.Compile("a+")
+	fmt.Println(err != nil, rx.MatchString("caat"))
+	s, err := template.RenderString("{{.}}!", "hi")
+	fmt.Println(s, err == nil)
+}
+`)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	expected := []string{`{"v":1} true`, "1 true true", "true true", "hi! true"}
+	for i, want := range expected {
+		if i >= len(lines) || strings.TrimSpace(lines[i]) != want {
+			t.Errorf("line %d: want %q, got %q", i, want, safeIndex(lines, i))
+		}
+	}
+}
+
+func TestSelectSendConvertsAndCopies(t *testing.T) {
+	out := runAndCapture(t, `package main
+import "fmt"
+type P struct{ X int }
+func main() {
+	fc := make(chan float64, 1)
+	select { case fc <- 1: }
+	fmt.Println(fmt.Sprintf("%T", <-fc))
+	pc := make(chan P, 1)
+	p := P{1}
+	select { case pc <- p: }
+	p.X = 2
+	fmt.Println((<-pc).X)
+}
+`)
+	if strings.TrimSpace(out) != "float64\n1" {
+		t.Errorf("unexpected output %q", out)
+	}
+}
+
+func TestSendOnClosedChannelPanics(t *testing.T) {
+	out := runAndCapture(t, `package main
+import "fmt"
+func try(f func()) {
+	defer func() { fmt.Println("recovered:", recover()) }()
+	f()
+}
+func main() {
+	c := make(chan int, 1)
+	close(c)
+	try(func() { select { case c <- 1: default: } })
+	try(func() { c <- 1 })
+}
+`)
+	want := "recovered: send on closed channel\nrecovered: send on closed channel"
+	if strings.TrimSpace(out) != want {
+		t.Errorf("unexpected output %q", out)
+	}
+}
+
+func TestMethodCallOnNilInterfacePanics(t *testing.T) {
+	out := runAndCapture(t, `package main
+import "fmt"
+type Shape interface{ Area() int }
+type Node struct{ next *Node }
+func (n *Node) Len() int { if n == nil { return 0 }; return 1 + n.next.Len() }
+func main() {
+	var n *Node
+	fmt.Println(n.Len())
+	defer func() { fmt.Println("recovered:", recover()) }()
+	var s Shape
+	s.Area()
+}
+`)
+	want := "0\nrecovered: runtime error: invalid memory address or nil pointer dereference"
+	if strings.TrimSpace(out) != want {
+		t.Errorf("want %q, got %q", want, out)
+	}
+}
+
+func TestUntypedConstantsFitTypedVars(t *testing.T) {
+	out := runAndCapture(t, `package main
+import ("fmt"; "math")
+type Small int8
+func main() {
+	var u uint64 = math.MaxUint64
+	var s Small = -128
+	var f float32 = 1 << 30
+	var b uint8
+	b = 255
+	fmt.Println(u, s, f, b, uint32(1<<32-1))
+}
+`)
+	if want := "18446744073709551615 -128 1.0737418e+09 255 4294967295"; strings.TrimSpace(out) != want {
+		t.Errorf("want %q, got %q", want, out)
+	}
+}
+
+func TestDefinedTypeIdentity(t *testing.T) {
+	out := runAndCapture(t, `package main
+import "fmt"
+type W int
+type V int
+func main() {
+	w := W(1)
+	fmt.Println(any(w) == any(1), any(w) == any(W(1)), any(w) == any(V(1)), w == 1, w != 2)
+	m := map[any]string{}
+	m[W(1)] = "W"
+	m[1] = "int"
+	fmt.Println(len(m), m[W(1)], m[1])
+	switch w { case 1: fmt.Println("one") }
+	fmt.Println(fmt.Sprintf("%T %T %d", w, 1, w))
+	var x any = W(3)
+	fmt.Println(x == 3, x == W(3), any(w) == 1)
+	switch x { case 3: fmt.Println("int 3"); case W(3): fmt.Println("W 3") }
+}
+`)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	expected := []string{"false true false true true", "2 W int", "one", "main.W int 1", "false true false", "W 3"}
+	for i, want := range expected {
+		if i >= len(lines) || strings.TrimSpace(lines[i]) != want {
+			t.Errorf("line %d: want %q, got %q", i, want, safeIndex(lines, i))
+		}
+	}
+}
+
+func TestPercentTPrintsInterpretedTypes(t *testing.T) {
+	out := runAndCapture(t, `package main
+import ("fmt"; "sync")
+type Pair[K comparable, V any] struct{ k K; v V }
+type Node struct{ n int }
+type ID int
+func main() {
+	fmt.Println(fmt.Sprintf("%T|%T|%T|%T", Pair[string, int]{}, &Node{}, []Node{}, map[string]*Node{}))
+	var wg sync.WaitGroup
+	fmt.Println(fmt.Sprintf("%T|%T|%T|%T", [2]ID{}, func(a int, s string) bool { return true }, &wg, nil))
+}
+`)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	expected := []string{
+		"main.Pair[string,int]|*main.Node|[]main.Node|map[string]*main.Node",
+		"[2]main.ID|func(int, string) bool|*sync.WaitGroup|<nil>",
+	}
+	for i, want := range expected {
+		if i >= len(lines) || strings.TrimSpace(lines[i]) != want {
+			t.Errorf("line %d: want %q, got %q", i, want, safeIndex(lines, i))
+		}
+	}
+}
+
+func TestLoopStructVariablePerIteration(t *testing.T) {
+	out := runAndCapture(t, `package main
+import "fmt"
+type P struct{ N int }
+func main() {
+	var ps []*P
+	for p := (P{}); p.N < 3; p.N++ { ps = append(ps, &p) }
+	fmt.Println(ps[0].N, ps[1].N, ps[2].N)
+	var as []*[1]int
+	for a := [1]int{}; a[0] < 2; a[0]++ { as = append(as, &a) }
+	fmt.Println(as[0][0], as[1][0])
+}
+`)
+	if want := "0 1 2\n0 1"; strings.TrimSpace(out) != want {
+		t.Errorf("want %q, got %q", want, out)
+	}
+}
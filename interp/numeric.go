@@ -0,0 +1,246 @@
+// interp/numeric.go
+package interp
+
+import (
+	"cmp"
+	"fmt"
+	"go/token"
+)
+
+// Numbers of every Go numeric type are represented by the host type of the
+// same name, so arithmetic wraps around exactly like compiled Go. byte and
+// rune are aliases of uint8 and int32. Plain int and float64 double as the
+// default types of untyped constants.
+
+type integer interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 | ~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr
+}
+type float interface { ~float32 | ~float64 }
+type complexNum interface { ~complex64 | ~complex128 }
+
+// canonicalType resolves the byte and rune aliases.
+func canonicalType(typ string) string {
+	switch typ {
+	case "byte": return "uint8"
+	case "rune": return "int32"
+	}
+	return typ
+}
+
+func isNumericType(typ string) bool { return numericZero[canonicalType(typ)] != nil }
+
+var numericZero = map[string]any{
+	"int": 0, "int8": int8(0), "int16": int16(0), "int32": int32(0), "int64": int64(0),
+	"uint": uint(0), "uint8": uint8(0), "uint16": uint16(0), "uint32": uint32(0), "uint64": uint64(0), "uintptr": uintptr(0),
+	"float32": float32(0), "float64": 0.0, "complex64": complex64(0), "complex128": complex128(0),
+}
+
+// numericType returns the type name of a number, or "" for other values.
+func numericType(v any) string {
+	switch v.(type) {
+	case int: return "int"
+	case int8: return "int8"
+	case int16: return "int16"
+	case int32: return "int32"
+	case int64: return "int64"
+	case uint: return "uint"
+	case uint8: return "uint8"
+	case uint16: return "uint16"
+	case uint32: return "uint32"
+	case uint64: return "uint64"
+	case uintptr: return "uintptr"
+	case float32: return "float32"
+	case float64: return "float64"
+	case complex64: return "complex64"
+	case complex128: return "complex128"
+	}
+	return ""
+}
+
+func isNumber(v any) bool { return numericType(v) != "" }
+
+// intBits returns the two's complement bits of an integer and whether its
+// type is signed. Other values go through ToInt.
+func intBits(v any) (uint64, bool) {
+	switch x := v.(type) {
+	case int: return uint64(x), true
+	case int8: return uint64(x), true
+	case int16: return uint64(x), true
+	case int32: return uint64(x), true
+	case int64: return uint64(x), true
+	case uint: return uint64(x), false
+	case uint8: return uint64(x), false
+	case uint16: return uint64(x), false
+	case uint32: return uint64(x), false
+	case uint64: return x, false
+	case uintptr: return uint64(x), false
+	}
+	return uint64(ToInt(v)), true
+}
+
+// convertNumber converts a number to the numeric type typ like a Go
+// conversion: integers wrap around, floats truncate toward zero.
+func convertNumber(typ string, v any) any {
+	switch x := v.(type) {
+	case float32: return floatTo(typ, float64(x))
+	case float64: return floatTo(typ, x)
+	case complex64: return floatTo(typ, float64(real(x)))
+	case complex128:
+		switch canonicalType(typ) {
+		case "complex64": return complex64(x)
+		case "complex128": return x
+		}
+		return floatTo(typ, real(x))
+	}
+	b, signed := intBits(v)
+	switch canonicalType(typ) {
+	case "int": return int(b)
+	case "int8": return int8(b)
+	case "int16": return int16(b)
+	case "int32": return int32(b)
+	case "int64": return int64(b)
+	case "uint": return uint(b)
+	case "uint8": return uint8(b)
+	case "uint16": return uint16(b)
+	case "uint32": return uint32(b)
+	case "uint64": return b
+	case "uintptr": return uintptr(b)
+	}
+	if signed { return floatTo(typ, float64(int64(b))) }
+	return floatTo(typ, float64(b))
+}
+
+func floatTo(typ string, f float64) any {
+	switch canonicalType(typ) {
+	case "float32": return float32(f)
+	case "float64": return f
+	case "complex64": return complex64(complex(f, 0))
+	case "complex128": return complex(f, 0)
+	}
+	if f < 0 { return convertNumber(typ, int64(f)) }
+	return convertNumber(typ, uint64(f))
+}
+
+// numberAs converts v to typ when both are numeric and leaves it unchanged
+// otherwise, for storing untyped constants into typed locations.
+func numberAs(typ string, v any) any {
+	if isNumericType(typ) && isNumber(v) { return convertNumber(typ, v) }
+	return v
+}
+
+// numericOp applies a binary operator to two numbers. Operands of different
+// types are unified first: an int or float64, as produced by an untyped
+// constant, takes the type of the other operand.
+func numericOp(op token.Token, l, r any) (any, error) {
+	if op == token.SHL || op == token.SHR {
+		n, signed := intBits(r)
+		if signed && int64(n) < 0 { return nil, &panicError{value: "runtime error: negative shift amount"} }
+		return shiftOp(op, l, n)
+	}
+	if lt, rt := numericType(l), numericType(r); lt != rt {
+		if rt == "int" || rt == "float64" && lt != "int" { r = convertNumber(lt, r) } else { l = convertNumber(rt, l) }
+	}
+	switch a := l.(type) {
+	case int: return intOp(op, a, r.(int))
+	case int8: return intOp(op, a, r.(int8))
+	case int16: return intOp(op, a, r.(int16))
+	case int32: return intOp(op, a, r.(int32))
+	case int64: return intOp(op, a, r.(int64))
+	case uint: return intOp(op, a, r.(uint))
+	case uint8: return intOp(op, a, r.(uint8))
+	case uint16: return intOp(op, a, r.(uint16))
+	case uint32: return intOp(op, a, r.(uint32))
+	case uint64: return intOp(op, a, r.(uint64))
+	case uintptr: return intOp(op, a, r.(uintptr))
+	case float32: return floatOp(op, a, r.(float32))
+	case float64: return floatOp(op, a, r.(float64))
+	case complex64: return complexOp(op, a, r.(complex64))
+	case complex128: return complexOp(op, a, r.(complex128))
+	}
+	return nil, NewRuntimeError(fmt.Sprintf("invalid operation: operator %s on %T", op, l))
+}
+
+// unaryOp applies -, + or ^ to a number of any type.
+func unaryOp(op token.Token, v any) (any, error) {
+	switch op {
+	case token.ADD: return v, nil
+	case token.SUB: return numericOp(token.SUB, convertNumber(numericType(v), 0), v)
+	case token.XOR: return numericOp(token.XOR, v, convertNumber(numericType(v), -1))
+	}
+	return nil, NewRuntimeError("unsupported unary op")
+}
+
+func intOp[T integer](op token.Token, a, b T) (any, error) {
+	switch op {
+	case token.ADD: return a + b, nil
+	case token.SUB: return a - b, nil
+	case token.MUL: return a * b, nil
+	case token.QUO, token.REM:
+		if b == 0 { return nil, &panicError{value: "runtime error: integer divide by zero"} }
+		if op == token.QUO { return a / b, nil }
+		return a % b, nil
+	case token.AND: return a & b, nil
+	case token.OR: return a | b, nil
+	case token.XOR: return a ^ b, nil
+	case token.AND_NOT: return a &^ b, nil
+	}
+	return compareOp(op, a, b)
+}
+
+func floatOp[T float](op token.Token, a, b T) (any, error) {
+	switch op {
+	case token.ADD: return a + b, nil
+	case token.SUB: return a - b, nil
+	case token.MUL: return a * b, nil
+	case token.QUO: return a / b, nil
+	}
+	return compareOp(op, a, b)
+}
+
+func complexOp[T complexNum](op token.Token, a, b T) (any, error) {
+	switch op {
+	case token.ADD: return a + b, nil
+	case token.SUB: return a - b, nil
+	case token.MUL: return a * b, nil
+	case token.QUO: return a / b, nil
+	case token.EQL: return a == b, nil
+	case token.NEQ: return a != b, nil
+	}
+	return nil, NewRuntimeError(fmt.Sprintf("invalid operation: operator %s not defined on %T", op, a))
+}
+
+func compareOp[T cmp.Ordered](op token.Token, a, b T) (any, error) {
+	switch op {
+	case token.EQL: return a == b, nil
+	case token.NEQ: return a != b, nil
+	case token.LSS: return a < b, nil
+	case token.GTR: return a > b, nil
+	case token.LEQ: return a <= b, nil
+	case token.GEQ: return a >= b, nil
+	}
+	return nil, NewRuntimeError(fmt.Sprintf("invalid operation: operator %s not defined on %T", op, a))
+}
+
+// shiftOp shifts an integer of any type; the result keeps its type, and
+// right shifts of unsigned values fill with zeros.
+func shiftOp(op token.Token, v any, n uint64) (any, error) {
+	switch a := v.(type) {
+	case int: return shift(op, a, n), nil
+	case int8: return shift(op, a, n), nil
+	case int16: return shift(op, a, n), nil
+	case int32: return shift(op, a, n), nil
+	case int64: return shift(op, a, n), nil
+	case uint: return shift(op, a, n), nil
+	case uint8: return shift(op, a, n), nil
+	case uint16: return shift(op, a, n), nil
+	case uint32: return shift(op, a, n), nil
+	case uint64: return shift(op, a, n), nil
+	case uintptr: return shift(op, a, n), nil
+	}
+	return nil, NewRuntimeError(fmt.Sprintf("invalid operation: shift of type %T", v))
+}
+
+func shift[T integer](op token.Token, a T, n uint64) T {
+	if op == token.SHL { return a << n }
+	return a >> n
+}
@@ -4,19 +4,25 @@ package interp
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"go/constant"
+	"go/token"
 	"math"
 	mrand "math/rand"
 	"regexp"
 	"sort"
+	"strconv"
 	strlib "strings"
 	"sync"
 	"text/template"
 	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
 // RegisterBuiltinPackages installs a tiny, curated set of std-like packages:
-// fmt, time, math, encoding/json, sync, regexp, strings, sort, math/rand, browser, text/template, http, storage.
+// fmt, errors, strconv, cmp, iter, slices, maps, time, math, encoding/json, sync, regexp, strings, unicode, unicode/utf8, sort, math/rand, browser, text/template, http, storage.
 func RegisterBuiltinPackages(vm *Interpreter) {
 
 	// --- fmt ---
@@ -26,7 +32,7 @@ func RegisterBuiltinPackages(vm *Interpreter) {
 		out := ""
 		for i, a := range args {
 			if i > 0 { out += " " }
-			out += ToString(a)
+			if s, ok := vm.stringOf(a); ok { out += s } else { out += ToString(a) }
 		}
 		// Reuse ConsoleLog via host
 		if nfun, ok := vm.natives["ConsoleLog"]; ok { _, _ = nfun([]any{out}) }
@@ -34,8 +40,7 @@ func RegisterBuiltinPackages(vm *Interpreter) {
 	}}
 	fmtPkg.Funcs["Printf"] = &Function{Name: "Printf", IsVariadic: true, Native: func(args []any) (any, error) {
 		if len(args) == 0 { return 0, nil }
-		format := ToString(args[0])
-		rest := args[1:]
+		format, rest := vm.fmtArgs(ToString(args[0]), args[1:])
 		// Use host-provided sprintf wrapper to avoid re-implementing format parsing
 		sp, ok := vm.natives["__hostSprintf"]
 		if !ok { return 0, NewRuntimeError("host sprintf not available") }
@@ -47,13 +52,131 @@ func RegisterBuiltinPackages(vm *Interpreter) {
 	}}
 	fmtPkg.Funcs["Sprintf"] = &Function{Name: "Sprintf", IsVariadic: true, Native: func(args []any) (any, error) {
 		if len(args) == 0 { return "", nil }
-		format := ToString(args[0]); rest := args[1:]
+		format, rest := vm.fmtArgs(ToString(args[0]), args[1:])
 		sp, ok := vm.natives["__hostSprintf"]; if !ok { return "", NewRuntimeError("host sprintf not available") }
 		res, err := sp(append([]any{format}, rest...)); if err != nil { return "", err }
 		return ToString(res), nil
 	}}
+	fmtPkg.Funcs["Errorf"] = &Function{Name: "Errorf", IsVariadic: true, Native: func(args []any) (any, error) {
+		if len(args) == 0 { return errors.New(""), nil }
+		format, rest := vm.fmtArgs(ToString(args[0]), args[1:])
+		sp, ok := vm.natives["__hostSprintf"]; if !ok { return nil, NewRuntimeError("host sprintf not available") }
+		res, err := sp(append([]any{format}, rest...)); if err != nil { return nil, err }
+		return errors.New(ToString(res)), nil
+	}}
 	vm.RegisterPackage("fmt", fmtPkg)
 
+	// --- errors ---
+	errorsPkg := &Package{Name: "errors", Funcs: map[string]*Function{}}
+	errorsPkg.Funcs["New"] = &Function{Name: "New", Params: []string{"text"}, Native: func(args []any) (any, error) {
+		return errors.New(ToString(args[0])), nil
+	}}
+	vm.RegisterPackage("errors", errorsPkg)
+
+	// --- strconv --- (subset; fallible conversions return (value, error) tuples)
+	strconvPkg := &Package{Name: "strconv", Funcs: map[string]*Function{}}
+	strconvPkg.Funcs["Atoi"] = &Function{Name: "Atoi", Params: []string{"s"}, Native: func(args []any) (any, error) {
+		n, err := strconv.Atoi(ToString(args[0]))
+		return &TupleVal{Values: []any{n, errValue(err)}}, nil
+	}}
+	strconvPkg.Funcs["Itoa"] = &Function{Name: "Itoa", Params: []string{"i"}, Native: func(args []any) (any, error) {
+		return strconv.Itoa(ToInt(args[0])), nil
+	}}
+	strconvPkg.Funcs["ParseFloat"] = &Function{Name: "ParseFloat", Params: []string{"s","bitSize"}, Native: func(args []any) (any, error) {
+		f, err := strconv.ParseFloat(ToString(args[0]), 64)
+		return &TupleVal{Values: []any{f, errValue(err)}}, nil
+	}}
+	strconvPkg.Funcs["ParseBool"] = &Function{Name: "ParseBool", Params: []string{"str"}, Native: func(args []any) (any, error) {
+		b, err := strconv.ParseBool(ToString(args[0]))
+		return &TupleVal{Values: []any{b, errValue(err)}}, nil
+	}}
+	strconvPkg.Funcs["Quote"] = &Function{Name: "Quote", Params: []string{"s"}, Native: func(args []any) (any, error) {
+		return strconv.Quote(ToString(args[0])), nil
+	}}
+	vm.RegisterPackage("strconv", strconvPkg)
+
+	// --- cmp --- (Ordered constraint for generic code, Compare and Less)
+	orderedType := &TypeDef{Name: "Ordered", Kind: "interface", Methods: map[string]*Function{}, Embeds: []string{"~int | ~float64 | ~string | ~byte"}}
+	cmpPkg := &Package{Name: "cmp", Funcs: map[string]*Function{}, Types: map[string]*TypeDef{"Ordered": orderedType}}
+	cmpPkg.Funcs["Compare"] = &Function{Name: "Compare", Params: []string{"x","y"}, Native: func(args []any) (any, error) {
+		if lessKey(args[0], args[1]) { return -1, nil }
+		if lessKey(args[1], args[0]) { return 1, nil }
+		return 0, nil
+	}}
+	cmpPkg.Funcs["Less"] = &Function{Name: "Less", Params: []string{"x","y"}, Native: func(args []any) (any, error) {
+		return lessKey(args[0], args[1]), nil
+	}}
+	vm.RegisterPackage("cmp", cmpPkg)
+	vm.types[orderedType.Name] = orderedType
+
+	// --- iter, slices, maps --- (range-over-func iterators and helpers)
+	iterPkg := &Package{Name: "iter", Types: map[string]*TypeDef{
+		"Seq": {Name: "Seq", Kind: "named", Underlying: "func(yield func(V) bool)", TypeParams: []string{"V"}},
+		"Seq2": {Name: "Seq2", Kind: "named", Underlying: "func(yield func(K, V) bool)", TypeParams: []string{"K", "V"}},
+	}}
+	vm.RegisterPackage("iter", iterPkg)
+	slicesPkg := &Package{Name: "slices", Funcs: map[string]*Function{}}
+	slicesPkg.Funcs["All"] = &Function{Name: "All", Params: []string{"s"}, Native: func(args []any) (any, error) {
+		data := sliceData(args[0])
+		return vm.seqFunc(func(yield func(...any) (bool, error)) error {
+			for i, v := range data { if ok, err := yield(i, v); !ok { return err } }
+			return nil
+		}), nil
+	}}
+	slicesPkg.Funcs["Values"] = &Function{Name: "Values", Params: []string{"s"}, Native: func(args []any) (any, error) {
+		data := sliceData(args[0])
+		return vm.seqFunc(func(yield func(...any) (bool, error)) error {
+			for _, v := range data { if ok, err := yield(v); !ok { return err } }
+			return nil
+		}), nil
+	}}
+	slicesPkg.Funcs["Backward"] = &Function{Name: "Backward", Params: []string{"s"}, Native: func(args []any) (any, error) {
+		data := sliceData(args[0])
+		return vm.seqFunc(func(yield func(...any) (bool, error)) error {
+			for i := len(data)-1; i >= 0; i-- { if ok, err := yield(i, data[i]); !ok { return err } }
+			return nil
+		}), nil
+	}}
+	slicesPkg.Funcs["Collect"] = &Function{Name: "Collect", Params: []string{"seq"}, Native: func(args []any) (any, error) {
+		return vm.collect(args[0])
+	}}
+	slicesPkg.Funcs["Sorted"] = &Function{Name: "Sorted", Params: []string{"seq"}, Native: func(args []any) (any, error) {
+		s, err := vm.collect(args[0]); if err != nil { return nil, err }
+		sort.SliceStable(s.Data, func(i, j int) bool { return lessKey(s.Data[i], s.Data[j]) })
+		return s, nil
+	}}
+	vm.RegisterPackage("slices", slicesPkg)
+	mapsPkg := &Package{Name: "maps", Funcs: map[string]*Function{}}
+	// mapSeq iterates over a map's entries, passing what pick selects from each to yield.
+	mapSeq := func(m any, pick func(k, v any) []any) *Function {
+		return vm.seqFunc(func(yield func(...any) (bool, error)) error {
+			mv, _ := underlying(m).(*MapVal); if mv == nil { return nil }
+			for _, hk := range keysOfMap(mv) {
+				v, ok := mv.Data[hk]; if !ok { continue } // deleted during iteration
+				if ok, err := yield(pick(mv.Keys[hk], v)...); !ok { return err }
+			}
+			return nil
+		})
+	}
+	mapsPkg.Funcs["All"] = &Function{Name: "All", Params: []string{"m"}, Native: func(args []any) (any, error) {
+		return mapSeq(args[0], func(k, v any) []any { return []any{k, v} }), nil
+	}}
+	mapsPkg.Funcs["Keys"] = &Function{Name: "Keys", Params: []string{"m"}, Native: func(args []any) (any, error) {
+		return mapSeq(args[0], func(k, v any) []any { return []any{k} }), nil
+	}}
+	mapsPkg.Funcs["Values"] = &Function{Name: "Values", Params: []string{"m"}, Native: func(args []any) (any, error) {
+		return mapSeq(args[0], func(k, v any) []any { return []any{v} }), nil
+	}}
+	mapsPkg.Funcs["Collect"] = &Function{Name: "Collect", Params: []string{"seq"}, Native: func(args []any) (any, error) {
+		m := &MapVal{KeyType: "any", ElementType: "any", Data: map[string]any{}, Keys: map[string]any{}}
+		add := func(kv []any) {
+			if len(m.Data) == 0 { m.KeyType, m.ElementType = typeOfValue(vm, kv[0]), typeOfValue(vm, kv[1]) }
+			m.setByKey(kv[0], kv[1])
+		}
+		return m, vm.drain(args[0], add, 2)
+	}}
+	vm.RegisterPackage("maps", mapsPkg)
+
 	// --- time ---
 	timePkg := &Package{Name: "time", Funcs: map[string]*Function{}, Vars: map[string]any{}}
 	timePkg.Funcs["Now"] = &Function{Name: "Now", Native: func(args []any) (any, error) {
@@ -68,6 +191,13 @@ func RegisterBuiltinPackages(vm *Interpreter) {
 		startMs := ToInt(args[0])
 		return int(time.Since(time.UnixMilli(int64(startMs))).Milliseconds()), nil
 	}}
+	// After(ms) returns a channel that receives the current time once ms have elapsed.
+	timePkg.Funcs["After"] = &Function{Name: "After", Params: []string{"d"}, Native: func(args []any) (any, error) {
+		ch := &ChannelVal{ElementType: "int", C: make(chan any, 1)}
+		d := 0; if len(args) > 0 { d = ToInt(args[0]) }
+		time.AfterFunc(time.Duration(d)*time.Millisecond, func() { ch.C <- int(time.Now().UnixMilli()) })
+		return ch, nil
+	}}
 	vm.RegisterPackage("time", timePkg)
 
 	// --- math ---
@@ -77,6 +207,23 @@ func RegisterBuiltinPackages(vm *Interpreter) {
 	mathPkg.Funcs["Sin"] = &Function{Name: "Sin", Native: func(args []any) (any, error) { return math.Sin(ToFloat(args[0])), nil }}
 	mathPkg.Funcs["Cos"] = &Function{Name: "Cos", Native: func(args []any) (any, error) { return math.Cos(ToFloat(args[0])), nil }}
 	mathPkg.Funcs["Abs"] = &Function{Name: "Abs", Native: func(args []any) (any, error) { return math.Abs(ToFloat(args[0])), nil }}
+	// Constants keep their exact value, like Go's untyped constants.
+	float := func(lit string) constant.Value { return constant.MakeFromLiteral(lit, token.FLOAT, 0) }
+	mathPkg.Consts = map[string]constant.Value{
+		"Pi": float("3.14159265358979323846264338327950288419716939937510582097494459"),
+		"E": float("2.71828182845904523536028747135266249775724709369995957496696763"),
+		"Sqrt2": float("1.41421356237309504880168872420969807856967187537694807317667974"),
+		"Ln2": float("0.693147180559945309417232121458176568075500134360255254120680009"),
+		"MaxFloat64": constant.MakeFloat64(math.MaxFloat64), "SmallestNonzeroFloat64": constant.MakeFloat64(math.SmallestNonzeroFloat64),
+		"MaxFloat32": constant.MakeFloat64(math.MaxFloat32),
+		"MaxInt": constant.MakeInt64(math.MaxInt), "MinInt": constant.MakeInt64(math.MinInt), "MaxUint": constant.MakeUint64(math.MaxUint),
+		"MaxInt8": constant.MakeInt64(math.MaxInt8), "MinInt8": constant.MakeInt64(math.MinInt8),
+		"MaxInt16": constant.MakeInt64(math.MaxInt16), "MinInt16": constant.MakeInt64(math.MinInt16),
+		"MaxInt32": constant.MakeInt64(math.MaxInt32), "MinInt32": constant.MakeInt64(math.MinInt32),
+		"MaxInt64": constant.MakeInt64(math.MaxInt64), "MinInt64": constant.MakeInt64(math.MinInt64),
+		"MaxUint8": constant.MakeUint64(math.MaxUint8), "MaxUint16": constant.MakeUint64(math.MaxUint16),
+		"MaxUint32": constant.MakeUint64(math.MaxUint32), "MaxUint64": constant.MakeUint64(math.MaxUint64),
+	}
 	vm.RegisterPackage("math", mathPkg)
 
 	// --- math/rand --- (small facade)
@@ -92,19 +239,24 @@ func RegisterBuiltinPackages(vm *Interpreter) {
 
 	// --- encoding/json --- (very small facade)
 	jsonPkg := &Package{Name: "encoding/json", Funcs: map[string]*Function{}}
-	// Marshal(v any) -> string
+	// Marshal(v any) -> (string, error)
 	jsonPkg.Funcs["Marshal"] = &Function{Name: "Marshal", Native: func(args []any) (any, error) {
-		if len(args) == 0 { return "null", nil }
-		b, err := json.Marshal(args[0])
-		if err != nil { return "", err }
-		return string(b), nil
+		if len(args) == 0 { return &TupleVal{Values: []any{"null", nil}}, nil }
+		b, err := json.Marshal(toNative(args[0]))
+		return &TupleVal{Values: []any{string(b), errValue(err)}}, nil
 	}}
-	// Unmarshal(s string) -> any   (NOTE: diverges from stdlib, returns value instead of filling a pointer)
+	// Unmarshal(data, &v) -> error fills v like the stdlib; Unmarshal(data) -> (any, error)
+	// returns the value instead. Objects and arrays come back as map[string]any and []any values.
 	jsonPkg.Funcs["Unmarshal"] = &Function{Name: "Unmarshal", Native: func(args []any) (any, error) {
-		if len(args) == 0 { return nil, nil }
+		if len(args) == 0 { return &TupleVal{Values: []any{nil, nil}}, nil }
+		data := []byte(ToString(args[0])); if _, ok := args[0].(*SliceVal); ok { data = bytesOf(args[0]) }
 		var v any
-		err := json.Unmarshal([]byte(ToString(args[0])), &v)
-		return v, err
+		err := json.Unmarshal(data, &v)
+		if len(args) < 2 { return &TupleVal{Values: []any{fromNative(v), errValue(err)}}, nil }
+		if err != nil { return err, nil }
+		p, ok := args[1].(*PointerVal); if !ok || p.Ref == nil { return errors.New("json: Unmarshal(non-pointer)"), nil }
+		if err := p.Ref.Set(fromNative(v)); err != nil { return err, nil }
+		return nil, nil
 	}}
 	vm.RegisterPackage("encoding/json", jsonPkg)
 	vm.RegisterPackage("json", jsonPkg) // convenience alias
@@ -135,6 +287,48 @@ func RegisterBuiltinPackages(vm *Interpreter) {
 	stringsPkg.Funcs["TrimSpace"] = &Function{Name: "TrimSpace", Params: []string{"s"}, Native: func(args []any) (any, error) { return strlib.TrimSpace(ToString(args[0])), nil }}
 	vm.RegisterPackage("strings", stringsPkg)
 
+	// --- unicode, unicode/utf8 --- (subset)
+	unicodePkg := &Package{Name: "unicode", Funcs: map[string]*Function{}, Consts: map[string]constant.Value{
+		"MaxRune": constant.MakeInt64(unicode.MaxRune), "ReplacementChar": constant.MakeInt64(unicode.ReplacementChar),
+	}}
+	for name, f := range map[string]func(rune) bool{
+		"IsLetter": unicode.IsLetter, "IsDigit": unicode.IsDigit, "IsNumber": unicode.IsNumber, "IsSpace": unicode.IsSpace,
+		"IsUpper": unicode.IsUpper, "IsLower": unicode.IsLower, "IsPunct": unicode.IsPunct, "IsControl": unicode.IsControl, "IsPrint": unicode.IsPrint,
+	} {
+		unicodePkg.Funcs[name] = &Function{Name: name, Params: []string{"r"}, Native: func(args []any) (any, error) { return f(rune(ToInt(args[0]))), nil }}
+	}
+	for name, f := range map[string]func(rune) rune{"ToUpper": unicode.ToUpper, "ToLower": unicode.ToLower, "ToTitle": unicode.ToTitle} {
+		unicodePkg.Funcs[name] = &Function{Name: name, Params: []string{"r"}, Native: func(args []any) (any, error) { return f(rune(ToInt(args[0]))), nil }}
+	}
+	vm.RegisterPackage("unicode", unicodePkg)
+
+	utf8Pkg := &Package{Name: "unicode/utf8", Funcs: map[string]*Function{}, Consts: map[string]constant.Value{
+		"RuneError": constant.MakeInt64(utf8.RuneError), "RuneSelf": constant.MakeInt64(utf8.RuneSelf),
+		"MaxRune": constant.MakeInt64(utf8.MaxRune), "UTFMax": constant.MakeInt64(utf8.UTFMax),
+	}}
+	utf8Pkg.Funcs["RuneCountInString"] = &Function{Name: "RuneCountInString", Params: []string{"s"}, Native: func(args []any) (any, error) { return utf8.RuneCountInString(ToString(args[0])), nil }}
+	utf8Pkg.Funcs["RuneCount"] = &Function{Name: "RuneCount", Params: []string{"p"}, Native: func(args []any) (any, error) { return utf8.RuneCount(bytesOf(args[0])), nil }}
+	utf8Pkg.Funcs["RuneLen"] = &Function{Name: "RuneLen", Params: []string{"r"}, Native: func(args []any) (any, error) { return utf8.RuneLen(rune(ToInt(args[0]))), nil }}
+	utf8Pkg.Funcs["ValidString"] = &Function{Name: "ValidString", Params: []string{"s"}, Native: func(args []any) (any, error) { return utf8.ValidString(ToString(args[0])), nil }}
+	utf8Pkg.Funcs["Valid"] = &Function{Name: "Valid", Params: []string{"p"}, Native: func(args []any) (any, error) { return utf8.Valid(bytesOf(args[0])), nil }}
+	utf8Pkg.Funcs["ValidRune"] = &Function{Name: "ValidRune", Params: []string{"r"}, Native: func(args []any) (any, error) { return utf8.ValidRune(rune(ToInt(args[0]))), nil }}
+	utf8Pkg.Funcs["DecodeRuneInString"] = &Function{Name: "DecodeRuneInString", Params: []string{"s"}, Native: func(args []any) (any, error) {
+		r, n := utf8.DecodeRuneInString(ToString(args[0])); return &TupleVal{Values: []any{r, n}}, nil
+	}}
+	utf8Pkg.Funcs["DecodeLastRuneInString"] = &Function{Name: "DecodeLastRuneInString", Params: []string{"s"}, Native: func(args []any) (any, error) {
+		r, n := utf8.DecodeLastRuneInString(ToString(args[0])); return &TupleVal{Values: []any{r, n}}, nil
+	}}
+	utf8Pkg.Funcs["DecodeRune"] = &Function{Name: "DecodeRune", Params: []string{"p"}, Native: func(args []any) (any, error) {
+		r, n := utf8.DecodeRune(bytesOf(args[0])); return &TupleVal{Values: []any{r, n}}, nil
+	}}
+	utf8Pkg.Funcs["AppendRune"] = &Function{Name: "AppendRune", Params: []string{"p", "r"}, Native: func(args []any) (any, error) {
+		out := &SliceVal{ElementType: "byte", Data: []any{}}
+		if s, ok := args[0].(*SliceVal); ok && s != nil { out.Data = append(out.Data, s.Data...) }
+		for _, b := range utf8.AppendRune(nil, rune(ToInt(args[1]))) { out.Data = append(out.Data, b) }
+		return out, nil
+	}}
+	vm.RegisterPackage("unicode/utf8", utf8Pkg)
+
 	// --- sort --- (Ints only, in-place)
 	sortPkg := &Package{Name: "sort", Funcs: map[string]*Function{}}
 	sortPkg.Funcs["Ints"] = &Function{Name: "Ints", Params: []string{"slice"}, Native: func(args []any) (any, error) {
@@ -179,9 +373,9 @@ func RegisterBuiltinPackages(vm *Interpreter) {
 	regPkg := &Package{Name: "regexp", Funcs: map[string]*Function{}, Types: map[string]*TypeDef{"Regexp": regexType}}
 	regPkg.Funcs["Compile"] = &Function{Name: "Compile", Params: []string{"pattern"}, Native: func(args []any) (any, error) {
 		r, err := regexp.Compile(ToString(args[0]))
-		if err != nil { return nil, err }
+		if err != nil { return &TupleVal{Values: []any{nil, err}}, nil }
 		// Store native pointer in field "__native"
-		return &StructVal{TypeName: "Regexp", Fields: map[string]any{"__native": r}}, nil
+		return &TupleVal{Values: []any{&StructVal{TypeName: "Regexp", Fields: map[string]any{"__native": r}}, nil}}, nil
 	}}
 	vm.RegisterPackage("regexp", regPkg)
 
@@ -319,41 +513,16 @@ func RegisterBuiltinPackages(vm *Interpreter) {
 	// --- text/template (simple RenderString helper) ---
 	tplPkg := &Package{Name: "text/template", Funcs: map[string]*Function{}}
 	tplPkg.Funcs["RenderString"] = &Function{Name: "RenderString", Native: func(args []any) (any, error) {
-		if len(args) == 0 { return "", nil }
+		if len(args) == 0 { return &TupleVal{Values: []any{"", nil}}, nil }
 		tmpl := ToString(args[0])
 		var data any = nil
 		if len(args) > 1 { data = args[1] }
-		// Convert interpreter runtime values (MapVal, SliceVal, StructVal) into native Go types
-		var convert func(any) any
-		convert = func(v any) any {
-			switch x := v.(type) {
-			case *MapVal:
-				out := map[string]any{}
-				for h, vv := range x.Data {
-					// original key may be stored in Keys map
-					orig := x.Keys[h]
-					keyStr := fmt.Sprintf("%v", orig)
-					out[keyStr] = convert(vv)
-				}
-				return out
-			case *SliceVal:
-				arr := make([]any, len(x.Data))
-				for i := range x.Data { arr[i] = convert(x.Data[i]) }
-				return arr
-			case *StructVal:
-				out := map[string]any{}
-				for k, vv := range x.Fields { out[k] = convert(vv) }
-				return out
-			default:
-				return v
-			}
-		}
 		t, err := template.New("tpl").Parse(tmpl)
-		if err != nil { return "", err }
+		if err != nil { return &TupleVal{Values: []any{"", err}}, nil }
 		var buf bytes.Buffer
-		nativeData := convert(data)
-		if err := t.Execute(&buf, nativeData); err != nil { return "", err }
-		return buf.String(), nil
+		nativeData := toNative(data)
+		if err := t.Execute(&buf, nativeData); err != nil { return &TupleVal{Values: []any{"", err}}, nil }
+		return &TupleVal{Values: []any{buf.String(), nil}}, nil
 	}}
 	vm.RegisterPackage("text/template", tplPkg)
 
@@ -392,6 +561,180 @@ func RegisterBuiltinPackages(vm *Interpreter) {
 	vm.RegisterPackage("storage", storPkg)
 }
 
+// errValue turns a host error into an interpreter value, keeping a nil
+// error as untyped nil so that err != nil behaves as expected.
+func errValue(err error) any {
+	if err == nil { return nil }
+	return err
+}
+
+// stringOf formats v with its Error or String method, as fmt does.
+// Methods with pointer receivers only apply to pointers.
+func (vm *Interpreter) stringOf(v any) (string, bool) {
+	if v == nil { return "", false }
+	for _, name := range []string{"Error", "String"} {
+		fn, _, err := vm.methodOf(v, name); if err != nil || len(fn.Params) != 0 { continue }
+		if _, isPtr := v.(*PointerVal); fn.RecvPtr && !isPtr { continue }
+		fn, recv, err := vm.selectMethod(nil, v, name, vm.globals); if err != nil { return "", false }
+		r, err := vm.callFunction(fn, vm.globals, &recv, nil); if err != nil { return "", false }
+		return ToString(r), true
+	}
+	return "", false
+}
+
+// fmtValue is an argument of a host format call that has an Error or
+// String method: %v, %s and %q print its result, other verbs the value.
+type fmtValue struct{ str string; v any }
+
+func (f fmtValue) Format(s fmt.State, verb rune) {
+	switch verb {
+	case 'v', 's', 'q': fmt.Fprintf(s, fmt.FormatString(s, verb), f.str)
+	default: fmt.Fprintf(s, fmt.FormatString(s, verb), f.v)
+	}
+}
+
+// fmtArgs prepares a format and its arguments for the host's fmt: values of
+// defined types are passed as their underlying value, and %T prints the
+// interpreted type of its argument rather than the host's.
+func (vm *Interpreter) fmtArgs(format string, args []any) (string, []any) {
+	out := make([]any, len(args))
+	for i, a := range args {
+		out[i] = underlying(a)
+		if s, ok := vm.stringOf(a); ok { out[i] = fmtValue{str: s, v: out[i]} }
+	}
+	f := []byte(format)
+	for i, n := 0, 0; i < len(f); i++ {
+		if f[i] != '%' { continue }
+		for i++; i < len(f) && strlib.IndexByte("+-# 0123456789.*", f[i]) >= 0; i++ { if f[i] == '*' { n++ } }
+		if i >= len(f) || f[i] == '[' { break } // explicit argument indexes are left alone
+		if f[i] == '%' { continue }
+		if f[i] == 'T' && n < len(args) && args[n] != nil { f[i] = 's'; out[n] = vm.goTypeName(typeOfValue(vm, args[n])) }
+		n++
+	}
+	return string(f), out
+}
+
+// goTypeName spells typ the way Go's %T does: declared types are qualified
+// with their package, main for the program's own, and type argument lists
+// have no spaces: main.Pair[string,int].
+func (vm *Interpreter) goTypeName(typ string) string {
+	typ = replaceTypeIdents(typ, func(name string) (string, bool) {
+		td := vm.types[name]; if td == nil { return "", false }
+		for _, pkg := range vm.packages { if pkg.Types[name] == td { return pkg.Name + "." + name, true } }
+		return "main." + name, true
+	})
+	var b strlib.Builder
+	var targs []bool // per open bracket: whether it lists type arguments
+	for i := 0; i < len(typ); i++ {
+		switch typ[i] {
+		case '[':
+			isMap := strlib.HasSuffix(typ[:i], "map") && (i == 3 || !isIdentByte(typ[i-4]))
+			targs = append(targs, i > 0 && isIdentByte(typ[i-1]) && !isMap)
+		case '(', '{':
+			targs = append(targs, false)
+		case ']', ')', '}':
+			if len(targs) > 0 { targs = targs[:len(targs)-1] }
+		case ' ':
+			if i > 0 && typ[i-1] == ',' && len(targs) > 0 && targs[len(targs)-1] { continue }
+		}
+		b.WriteByte(typ[i])
+	}
+	return b.String()
+}
+
+// seqFunc wraps a host loop as an iterator function for range-over-func.
+// each passes every element to yield and stops once it returns false.
+func (vm *Interpreter) seqFunc(each func(yield func(...any) (bool, error)) error) *Function {
+	return &Function{Name: "seq", Params: []string{"yield"}, Native: func(args []any) (any, error) {
+		yield, ok := underlying(args[0]).(*Function); if !ok { return nil, &panicError{value: nilDeref} }
+		return nil, each(func(a ...any) (bool, error) {
+			r, err := vm.callFunction(yield, nil, nil, a); return err == nil && ToBool(r), err
+		})
+	}}
+}
+
+// drain calls the iterator function seq with a yield that hands each group
+// of n values to f and never stops early.
+func (vm *Interpreter) drain(seq any, f func([]any), n int) error {
+	fn, ok := underlying(seq).(*Function); if !ok { return &panicError{value: nilDeref} }
+	_, err := vm.callFunction(fn, nil, nil, []any{&Function{Name: "yield", Native: func(args []any) (any, error) {
+		vals := make([]any, n); copy(vals, args); f(vals); return true, nil
+	}}})
+	return err
+}
+
+// collect gathers the values of an iter.Seq into a slice typed after the first one.
+func (vm *Interpreter) collect(seq any) (*SliceVal, error) {
+	s := &SliceVal{ElementType: "any"}
+	add := func(v []any) {
+		if len(s.Data) == 0 { s.ElementType = typeOfValue(vm, v[0]) }
+		s.Data = append(s.Data, copyValue(v[0]))
+	}
+	return s, vm.drain(seq, add, 1)
+}
+
+func sliceData(v any) []any { if s, ok := underlying(v).(*SliceVal); ok { return s.Data }; return nil }
+
+// bytesOf returns the contents of a []byte value.
+func bytesOf(v any) []byte {
+	s, ok := v.(*SliceVal); if !ok || s == nil { return nil }
+	b := make([]byte, len(s.Data))
+	for i, e := range s.Data { b[i] = byte(ToInt(e)) }
+	return b
+}
+
+// toNative converts interpreter runtime values (MapVal, SliceVal, StructVal)
+// into native Go types for host libraries such as json and text/template.
+func toNative(v any) any {
+	switch x := v.(type) {
+	case *NamedVal:
+		return toNative(x.Value)
+	case *MapVal:
+		out := map[string]any{}
+		for h, vv := range x.Data {
+			// original key may be stored in Keys map
+			orig := x.Keys[h]
+			keyStr := fmt.Sprintf("%v", underlying(orig))
+			out[keyStr] = toNative(vv)
+		}
+		return out
+	case *SliceVal:
+		arr := make([]any, len(x.Data))
+		for i := range x.Data { arr[i] = toNative(x.Data[i]) }
+		return arr
+	case *ArrayVal:
+		arr := make([]any, len(x.Data))
+		for i := range x.Data { arr[i] = toNative(x.Data[i]) }
+		return arr
+	case *StructVal:
+		out := map[string]any{}
+		for k, vv := range x.Fields { out[k] = toNative(vv) }
+		return out
+	case *PointerVal:
+		if x.Ref == nil { return nil }
+		return toNative(x.Ref.Get())
+	default:
+		return v
+	}
+}
+
+// fromNative is the inverse of toNative for decoded JSON: objects become
+// map[string]any and arrays []any, so interpreted code can index them.
+func fromNative(v any) any {
+	switch x := v.(type) {
+	case map[string]any:
+		m := &MapVal{KeyType: "string", ElementType: "any", Data: map[string]any{}, Keys: map[string]any{}}
+		for k, vv := range x { m.setByKey(k, fromNative(vv)) }
+		return m
+	case []any:
+		s := &SliceVal{ElementType: "any", Data: make([]any, len(x))}
+		for i := range x { s.Data[i] = fromNative(x[i]) }
+		return s
+	default:
+		return v
+	}
+}
+
 // ensureNativeWG returns the *sync.WaitGroup associated with a StructVal.
 func ensureNativeWG(v any) *sync.WaitGroup {
 	if sv, ok := v.(*StructVal); ok {
@@ -427,6 +770,9 @@ func (vm *Interpreter) resolvePackageSelector(pkg *Package, sel string) (any, bo
 	if pkg.Vars != nil {
 		if v, ok := pkg.Vars[sel]; ok { return v, true }
 	}
+	if c, ok := pkg.Consts[sel]; ok {
+		v, err := vm.constValue(constVal{c, untypedKind(c)}); return v, err == nil
+	}
 	return nil, false
 }
 
@@ -475,6 +821,24 @@ func (vm *Interpreter) installImportedPackage(alias, path string) {
 	case "storage":
 		if _, ok := vm.packages["storage"]; !ok { RegisterBuiltinPackages(vm) }
 		vm.globals.Vars[alias] = vm.packages["storage"]
+	case "errors":
+		if _, ok := vm.packages["errors"]; !ok { RegisterBuiltinPackages(vm) }
+		vm.globals.Vars[alias] = vm.packages["errors"]
+	case "strconv":
+		if _, ok := vm.packages["strconv"]; !ok { RegisterBuiltinPackages(vm) }
+		vm.globals.Vars[alias] = vm.packages["strconv"]
+	case "cmp":
+		if _, ok := vm.packages["cmp"]; !ok { RegisterBuiltinPackages(vm) }
+		vm.globals.Vars[alias] = vm.packages["cmp"]
+	case "iter", "slices", "maps":
+		if _, ok := vm.packages[path]; !ok { RegisterBuiltinPackages(vm) }
+		vm.globals.Vars[alias] = vm.packages[path]
+	case "unicode":
+		if _, ok := vm.packages["unicode"]; !ok { RegisterBuiltinPackages(vm) }
+		vm.globals.Vars[alias] = vm.packages["unicode"]
+	case "unicode/utf8":
+		if _, ok := vm.packages["unicode/utf8"]; !ok { RegisterBuiltinPackages(vm) }
+		vm.globals.Vars[alias] = vm.packages["unicode/utf8"]
 	default:
 		_ = fmt.Sprintf("unknown import: %s", path)
 	}
@@ -3,6 +3,8 @@ package interp
 
 import (
 	"fmt"
+	"go/token"
+	"sort"
 	"strings"
 )
 
@@ -16,19 +18,26 @@ type panicError struct{ value any }
 func (e *panicError) Error() string { return fmt.Sprintf("panic: %v", e.value) }
 
 // FieldDef/TypeDef describe simple struct types (name, fields, methods).
-type FieldDef struct{ Name, Type string }
+// An embedded field is named after its type and promotes its fields and methods.
+type FieldDef struct{ Name, Type string; Embedded bool }
 
 type TypeDef struct {
 	Name    string
-	Kind    string // "struct", "interface", "chan"
+	Kind    string // "struct", "interface", "chan", or "named" for other defined types
+	Underlying string // named: the type it is defined over, e.g. "float64" for type Celsius float64
 	Fields  []FieldDef
 	Methods map[string]*Function
+	MethodSet []string // interface: names of the methods an implementation needs
+	Signatures map[string]string // interface: func type per method, e.g. "func() float64"
+	Embeds    []string // interface: embedded constraint elements such as "~int | ~float64"
+	TypeParams []string // generic types: type parameter names in declaration order
 }
 
 // Function represents either a user-defined or native function.
 type Function struct {
 	Name          string
 	Params        []string
+	ParamTypes    []string // declared parameter types, parallel to Params (may be empty for natives)
 	IsVariadic    bool
 	Body          any // *ast.BlockStmt for user functions
 	Env           *Env
@@ -36,6 +45,13 @@ type Function struct {
 
 	RecvName      string // method receiver var name
 	RecvType      string // method receiver type (without "*")
+	RecvPtr       bool   // method declared with a pointer receiver
+
+	TypeParams    []string // generic functions/methods: type parameter names
+	Constraints   []string // constraint per type parameter
+	TypeArgs      []string // explicit instantiation, e.g. Map[int, string]
+	ResultTypes   []string // declared result types
+	ResultNames   []string // names of named results, parallel to ResultTypes
 }
 
 // StructVal, SliceVal, MapVal, ChannelVal are dynamic runtime containers.
@@ -49,6 +65,13 @@ type SliceVal struct {
 	Data        []any
 }
 
+// ArrayVal is a fixed-size array [N]T; len(Data) is N. Unlike slices,
+// arrays are values: assignment copies the elements.
+type ArrayVal struct {
+	ElementType string
+	Data        []any
+}
+
 type MapVal struct {
 	KeyType, ElementType string
 	Data map[string]any  // hashed key -> value
@@ -61,6 +84,53 @@ func (m *MapVal) getByKey(k any) (any, bool) {
 func (m *MapVal) setByKey(k, v any) { h := hashKey(k); m.Data[h] = v; m.Keys[h] = k }
 func (m *MapVal) deleteByKey(k any) { h := hashKey(k); delete(m.Data, h); delete(m.Keys, h) }
 
+// PointerVal points at an addressable location: a variable, a struct field,
+// a slice element or a fresh value from new(T) or &T{}. A nil pointer has
+// no Ref.
+type PointerVal struct {
+	ElementType string
+	Ref         Ref
+}
+
+// copyValue returns a copy of v as made by Go assignment: structs and
+// arrays are copied element by element, everything else is shared.
+func copyValue(v any) any {
+	switch x := v.(type) {
+	case *StructVal:
+		if x == nil { return v }
+		c := &StructVal{TypeName: x.TypeName, Fields: make(map[string]any, len(x.Fields))}
+		for k, f := range x.Fields { c.Fields[k] = copyValue(f) }
+		return c
+	case *ArrayVal:
+		c := &ArrayVal{ElementType: x.ElementType, Data: make([]any, len(x.Data))}
+		for i, e := range x.Data { c.Data[i] = copyValue(e) }
+		return c
+	case *NamedVal:
+		if _, ok := x.Value.(*ArrayVal); ok { return &NamedVal{TypeName: x.TypeName, Value: copyValue(x.Value)} }
+	}
+	return v
+}
+
+// NamedVal is a value of a defined type whose underlying type is not a
+// struct, as in type Celsius float64; Value holds the underlying value.
+type NamedVal struct {
+	TypeName string
+	Value    any
+}
+
+// underlying strips the defined type off a value.
+func underlying(v any) any {
+	if n, ok := v.(*NamedVal); ok { return n.Value }
+	return v
+}
+
+// TupleVal carries the results of a call that returns more than one value.
+// It only ever lives between a call site and the assignment, return or
+// argument list that unpacks it.
+type TupleVal struct {
+	Values []any
+}
+
 // ChannelVal models a typed channel with a Go channel underneath.
 type ChannelVal struct {
 	ElementType string
@@ -82,6 +152,13 @@ func hashKey(v any) string {
 		for i := 0; i < len(names); i++ { for j := i+1; j < len(names); j++ { if names[j] < names[i] { names[i], names[j] = names[j], names[i] } } }
 		for _, name := range names { b.WriteString(name); b.WriteByte('='); b.WriteString(hashKey(t.Fields[name])); b.WriteByte(';') }
 		return b.String()
+	case *ArrayVal:
+		var b strings.Builder
+		b.WriteString("array:")
+		for _, e := range t.Data { b.WriteString(hashKey(e)); b.WriteByte(';') }
+		return b.String()
+	case *PointerVal:	return fmt.Sprintf("p:%v", refAddr(t.Ref))
+	case *NamedVal:	return "n:" + t.TypeName + ":" + hashKey(t.Value)
 	default:	return fmt.Sprintf("u:%T:%v", v, v)
 	}
 }
@@ -93,7 +170,11 @@ func ToInt(v any) int {
 	case int:	return x
 	case int64:	return int(x)
 	case float64:	return int(x)
+	case float32:	return int(x)
+	case int8, int16, int32, uint, uint8, uint16, uint32, uint64, uintptr:
+		b, _ := intBits(x); return int(b)
 	case bool:	if x { return 1 } ; return 0
+	case *NamedVal:	return ToInt(x.Value)
 	case string:
 		n := 0; s := x; sign := 1
 		if len(s) > 0 && (s[0] == '+' || s[0] == '-') { if s[0] == '-' { sign = -1 }; s = s[1:] }
@@ -108,7 +189,11 @@ func ToFloat(v any) float64 {
 	case float64:	return x
 	case int:		return float64(x)
 	case int64:		return float64(x)
+	case float32:	return float64(x)
+	case int8, int16, int32, uint, uint8, uint16, uint32, uint64, uintptr:
+		return convertNumber("float64", x).(float64)
 	case bool:		if x { return 1 } ; return 0
+	case *NamedVal:	return ToFloat(x.Value)
 	case string:
 		s := x; sign := 1.0; i := 0
 		if len(s) > 0 && (s[0] == '+' || s[0] == '-') { if s[0] == '-' { sign = -1 }; i++ }
@@ -128,6 +213,7 @@ func ToBool(v any) bool {
 	case int64:	return x != 0
 	case float64:	return x != 0
 	case string:	return x != "" && x != "0" && x != "false"
+	case *NamedVal:	return ToBool(x.Value)
 	}
 	return v != nil
 }
@@ -140,15 +226,43 @@ func ToString(v any) string {
 	case float64:	return fmt.Sprintf("%g", x)
 	case bool:		if x { return "true" } ; return "false"
 	case *SliceVal:
-		if x.ElementType == "byte" {
-			b := make([]byte, len(x.Data))
-			for i := range b { b[i] = byte(ToInt(x.Data[i]) & 0xFF) }
-			return string(b)
-		}
+		parts := make([]string, len(x.Data))
+		for i, e := range x.Data { parts[i] = ToString(e) }
+		return "[" + strings.Join(parts, " ") + "]"
+	case *ArrayVal:
+		parts := make([]string, len(x.Data))
+		for i, e := range x.Data { parts[i] = ToString(e) }
+		return "[" + strings.Join(parts, " ") + "]"
+	case *MapVal:
+		// Like fmt, print entries ordered by key.
+		keys := make([]string, 0, len(x.Keys))
+		for h := range x.Keys { keys = append(keys, h) }
+		sort.Slice(keys, func(i, j int) bool { return lessKey(x.Keys[keys[i]], x.Keys[keys[j]]) })
+		parts := make([]string, len(keys))
+		for i, h := range keys { parts[i] = ToString(x.Keys[h]) + ":" + ToString(x.Data[h]) }
+		return "map[" + strings.Join(parts, " ") + "]"
+	case *NamedVal:
+		return ToString(x.Value)
+	case *PointerVal:
+		if x.Ref == nil { return "<nil>" }
+		if sv, ok := x.Ref.Get().(*StructVal); ok { return ToString(sv) }
+		return fmt.Sprintf("%p", x)
+	case nil:
+		return "<nil>"
 	}
 	return fmt.Sprintf("%v", v)
 }
 
+func lessKey(a, b any) bool {
+	a, b = underlying(a), underlying(b)
+	if isNumber(a) && isNumber(b) { r, _ := numericOp(token.LSS, a, b); return r == true }
+	switch x := a.(type) {
+	case int:	return x < ToInt(b)
+	case float64:	return x < ToFloat(b)
+	}
+	return ToString(a) < ToString(b)
+}
+
 func IsZero(v any) bool {
 	switch x := v.(type) {
 	case nil:		return true
@@ -157,9 +271,13 @@ func IsZero(v any) bool {
 	case bool:		return !x
 	case string:	return x == ""
 	case *SliceVal:	return len(x.Data) == 0
+	case *ArrayVal:	for _, e := range x.Data { if !IsZero(e) { return false } }; return true
 	case *MapVal:	return len(x.Data) == 0
 	case *StructVal:	return false
 	case *ChannelVal:	return x == nil
+	case *PointerVal:	return x.Ref == nil
+	case *NamedVal:	return IsZero(x.Value)
 	}
+	if isNumber(v) { return equals(v, 0) }
 	return v == nil
 }